@@ -0,0 +1,141 @@
+package stamets
+
+import (
+	"time"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// DegradationLevel describes how the configuration of an adaptive points-to analysis
+// was degraded to produce a result within the time budget. Levels are cumulative e.g.,
+// restricting queries to the main module also drops reflection.
+type DegradationLevel int
+
+const (
+	// DegradationNone analyzes the program as configured.
+	DegradationNone DegradationLevel = iota
+	// DegradationNoReflection drops the sound handling of reflection.
+	DegradationNoReflection
+	// DegradationMainModule restricts queries to the main module.
+	DegradationMainModule
+	// DegradationNoIndirectQueries drops indirect queries.
+	DegradationNoIndirectQueries
+	// DegradationVTA falls back to the call graph constructed via Variable Type Analysis,
+	// without points-to sets.
+	DegradationVTA
+	// DegradationCHA falls back to the call graph constructed via Class Hierarchy Analysis,
+	// without points-to sets.
+	DegradationCHA
+)
+
+// degradationLevels lists the names of degradation levels, as printed in PTA metrics.
+var degradationLevels = map[DegradationLevel]string{
+	DegradationNone:              "none",
+	DegradationNoReflection:      "no reflection",
+	DegradationMainModule:        "main module queries",
+	DegradationNoIndirectQueries: "no indirect queries",
+	DegradationVTA:               "VTA call graph",
+	DegradationCHA:               "CHA call graph",
+}
+
+func (l DegradationLevel) String() string {
+	return degradationLevels[l]
+}
+
+// degradation is an analysis configuration at a degradation level.
+type degradation struct {
+	level DegradationLevel
+	opts  ProgramOptions
+}
+
+// degradations lists the points-to analysis configurations at every degradation level, starting
+// from the given options. Levels which do not change the configuration are skipped.
+func degradations(opts ProgramOptions) []degradation {
+	ds := []degradation{{DegradationNone, opts}}
+	if opts.Reflection {
+		opts.Reflection = false
+		ds = append(ds, degradation{DegradationNoReflection, opts})
+	}
+	if opts.Scope == ScopeAll && opts.Module != "" {
+		opts.Scope = ScopeMainModule
+		ds = append(ds, degradation{DegradationMainModule, opts})
+	}
+	if !opts.SkipIndirectQueries {
+		opts.SkipIndirectQueries = true
+		ds = append(ds, degradation{DegradationNoIndirectQueries, opts})
+	}
+	return ds
+}
+
+// AnalyzeAdaptive runs the points-to analysis on an SSA program like AnalyzeProgram, degrading
+// the configuration whenever the analysis is likely to exceed the time budget. Every attempt is
+// given half of the remaining budget, such that degraded attempts may still complete, and no
+// attempt is started once the budget is exhausted. When no attempt completes, the call graph is
+// constructed via Variable Type Analysis in the remaining budget, or finally via Class Hierarchy
+// Analysis, and the result only includes the call graph.
+//
+// The degradation level of the configuration producing the result is recorded in the metrics,
+// and the duration covers all attempts. The budget only bounds the wall-clock time until a result
+// is returned, not CPU time or memory: the points-to analysis cannot be interrupted, so attempts
+// exceeding their limit are abandoned, like by AnalyzeWithTimeout, and keep running alongside later
+// attempts until they complete. The CHA call graph is always constructed to completion.
+func AnalyzeAdaptive(prog *ssa.Program, opts ProgramOptions, budget time.Duration) PTAMetrics {
+	return analyzeAdaptive(prog, opts, budget, nil)
+}
+
+// analyzeAdaptive runs the points-to analysis like AnalyzeAdaptive. If given, the attempt
+// predicate selects which configurations are attempted in their time limit.
+func analyzeAdaptive(prog *ssa.Program, opts ProgramOptions, budget time.Duration,
+	attempt func(*pointer.Config, time.Duration) bool) PTAMetrics {
+	start := time.Now()
+	mains, calls, err := programEntries(prog, opts)
+	if err != nil {
+		return PTAMetrics{
+			BaseMetrics: BaseMetrics[*pointer.Result]{
+				err: err,
+			},
+		}
+	}
+
+	done := func(m PTAMetrics, level DegradationLevel) PTAMetrics {
+		m.Degradation = level
+		m.SyntheticEntryCalls = calls
+		m.Duration = time.Since(start)
+		return m
+	}
+
+	for _, d := range degradations(opts) {
+		limit := (budget - time.Since(start)) / 2
+		if limit <= 0 {
+			break
+		}
+		config := pointerConfig(prog, mains, d.opts)
+		if attempt != nil && !attempt(config, limit) {
+			continue
+		}
+		analyze := d.opts.analyzer()
+		if m, ok := TaskWithTimeout(limit, func() PTAMetrics {
+			return analyze(config)
+		}); ok {
+			return done(m, d.level)
+		}
+	}
+
+	fallback := func(cg *callgraph.Graph) PTAMetrics {
+		return PTAMetrics{
+			BaseMetrics: BaseMetrics[*pointer.Result]{
+				Payload: &pointer.Result{CallGraph: cg},
+			},
+		}
+	}
+	if limit := budget - time.Since(start); limit > 0 {
+		if m, ok := TaskWithTimeout(limit, func() PTAMetrics {
+			return fallback(vtaCallGraph(prog))
+		}); ok {
+			return done(m, DegradationVTA)
+		}
+	}
+	return done(fallback(chaCallGraph(prog)), DegradationCHA)
+}
@@ -0,0 +1,135 @@
+package stamets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/pointer"
+)
+
+func TestDegradations(t *testing.T) {
+	ds := degradations(ProgramOptions{Reflection: true, Module: "example.com/mod"})
+	levels := make([]DegradationLevel, 0, len(ds))
+	for _, d := range ds {
+		levels = append(levels, d.level)
+	}
+	require.Equal(t, []DegradationLevel{
+		DegradationNone,
+		DegradationNoReflection,
+		DegradationMainModule,
+		DegradationNoIndirectQueries,
+	}, levels)
+
+	last := ds[len(ds)-1].opts
+	require.False(t, last.Reflection)
+	require.Equal(t, ScopeMainModule, last.Scope)
+	require.True(t, last.SkipIndirectQueries)
+
+	// Levels which do not change the configuration are skipped.
+	ds = degradations(ProgramOptions{Scope: ScopePackages, Packages: []string{"lib"}, SkipIndirectQueries: true})
+	require.Len(t, ds, 1)
+}
+
+func TestAnalyzeAdaptive(t *testing.T) {
+	prog, _ := buildLibrary(t)
+	opts := ProgramOptions{
+		Scope:      ScopePackages,
+		Packages:   []string{"lib"},
+		Synthetic:  true,
+		Reflection: true,
+	}
+
+	m := AnalyzeAdaptive(prog, opts, time.Minute)
+	require.True(t, m.Ok())
+	require.Equal(t, DegradationNone, m.Degradation)
+	require.Equal(t, 5, m.SyntheticEntryCalls)
+	require.NotZero(t, m.Queries)
+
+	// Without a budget, no analysis is started, and only the CHA call graph is constructed.
+	attempts := 0
+	m = analyzeAdaptive(prog, opts, 0, func(*pointer.Config, time.Duration) bool {
+		attempts++
+		return true
+	})
+	require.Zero(t, attempts)
+	require.True(t, m.Ok())
+	require.Equal(t, DegradationCHA, m.Degradation)
+	require.Zero(t, m.Queries)
+	require.NotNil(t, m.Payload.CallGraph)
+	require.Contains(t, m.String(), "- Degradation level: CHA call graph")
+
+	m = AnalyzeAdaptive(prog, ProgramOptions{}, time.Minute)
+	require.False(t, m.Ok())
+}
+
+func TestAnalyzeAdaptiveDegradations(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"example.com/dep": `package dep
+
+type T struct{ next *T }
+
+func New() *T { return &T{next: &T{}} }
+`,
+		"example.com/mod/app": `package main
+
+import "example.com/dep"
+
+func main() {
+	t := dep.New()
+	p := &t
+	println(*p)
+}
+`,
+	}, "example.com/dep", "example.com/mod/app")
+	opts := ProgramOptions{Module: "example.com/mod", Reflection: true}
+
+	// Rejecting the first k configurations degrades the analysis k levels.
+	levels := []DegradationLevel{
+		DegradationNone,
+		DegradationNoReflection,
+		DegradationMainModule,
+		DegradationNoIndirectQueries,
+	}
+	var ms []PTAMetrics
+	for k, level := range levels {
+		attempts, accepted := 0, (*pointer.Config)(nil)
+		m := analyzeAdaptive(prog, opts, time.Minute, func(config *pointer.Config, _ time.Duration) bool {
+			attempts++
+			if attempts <= k {
+				return false
+			}
+			accepted = config
+			return true
+		})
+		require.True(t, m.Ok())
+		require.Equal(t, level, m.Degradation)
+		require.Equal(t, k+1, attempts)
+		require.Equal(t, level == DegradationNone, accepted.Reflection)
+		ms = append(ms, m)
+	}
+
+	// Dropping reflection keeps the queries.
+	require.NotZero(t, ms[0].Queries)
+	require.Equal(t, ms[0].Queries, ms[1].Queries)
+	require.Equal(t, ms[0].IndirectQueries, ms[1].IndirectQueries)
+	// Values outside the main module are no longer queried.
+	require.Less(t, ms[2].Queries, ms[1].Queries)
+	require.NotZero(t, ms[2].IndirectQueries)
+	// Indirect queries are dropped, keeping the other queries.
+	require.Equal(t, ms[2].Queries, ms[3].Queries)
+	require.Zero(t, ms[3].IndirectQueries)
+
+	// Once every configuration is rejected, the VTA call graph is constructed.
+	attempts := 0
+	m := analyzeAdaptive(prog, opts, time.Minute, func(*pointer.Config, time.Duration) bool {
+		attempts++
+		return false
+	})
+	require.Equal(t, len(levels), attempts)
+	require.True(t, m.Ok())
+	require.Equal(t, DegradationVTA, m.Degradation)
+	require.Zero(t, m.Queries)
+	require.NotNil(t, m.Payload.CallGraph)
+	require.Contains(t, m.String(), "- Degradation level: VTA call graph")
+}
@@ -0,0 +1,168 @@
+package stamets
+
+import (
+	"fmt"
+	"go/types"
+	"strings"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// largestAliasClasses is the number of largest alias classes reported
+// in aliasing metrics.
+const largestAliasClasses = 10
+
+// AliasClass is a class of PTA queries with identical points-to sets.
+type AliasClass struct {
+	// Number of queries in the class
+	Queries int
+	// Size of the shared points-to set
+	Size int
+}
+
+// Aliasing encodes metrics about may-alias relations between PTA queries.
+type Aliasing struct {
+	// Number of pairs of queries with intersecting points-to sets
+	MayAliasPairs int
+	// Ratio of may-alias pairs among all pairs of queries
+	Density float64
+	// Number of distinct non-empty points-to sets
+	DistinctSets int
+	// Largest classes of queries sharing a non-empty points-to set, in descending order
+	Classes []AliasClass
+}
+
+func (a Aliasing) String() string {
+	str := fmt.Sprintf(`Aliasing metrics:
+	- May-alias query pairs: %d
+	- Alias density: %f
+	- Distinct points-to sets: %d
+	- Largest classes of queries sharing a points-to set:
+`,
+		a.MayAliasPairs,
+		a.Density,
+		a.DistinctSets,
+	)
+	for _, c := range a.Classes {
+		str += fmt.Sprintf("\t\t- %d queries sharing a set of size %d\n", c.Queries, c.Size)
+	}
+	return str
+}
+
+// labelKey identifies a points-to label. Labels are allocated anew whenever
+// points-to sets are enumerated, and cannot be compared by identity.
+type labelKey struct {
+	value ssa.Value
+	rtype types.Type
+	path  string
+	str   string
+}
+
+// pointsToClass is a hash-consed points-to set, shared by one or more queries.
+type pointsToClass struct {
+	// Representative points-to set
+	pts pointer.PointsToSet
+	// Identifiers of the labels in the set
+	labels []int
+	// Number of queries with the set
+	queries int
+}
+
+// AliasMetrics computes may-alias metrics over the PTA queries. Points-to sets are first
+// hash-consed by their labels, such that queries with identical sets are only considered once.
+// Pairs of sets sharing a label are then found via an inverted index from labels to sets,
+// and confirmed with PointsToSet.Intersects.
+func (m PTAMetrics) AliasMetrics() PTAMetrics {
+	res, err := m.queryResult()
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	ids := make(map[labelKey]int)
+	classes := make(map[string]*pointsToClass)
+	queries := 0
+	for _, pt := range res.Queries {
+		queries++
+		pts := pt.PointsTo()
+		labels := pts.Labels()
+		if len(labels) == 0 {
+			continue
+		}
+
+		set := make([]int, 0, len(labels))
+		for _, l := range labels {
+			k := labelKey{l.Value(), l.ReflectType(), l.Path(), l.String()}
+			if _, ok := ids[k]; !ok {
+				ids[k] = len(ids)
+			}
+			set = append(set, ids[k])
+		}
+		slices.Sort(set)
+		set = slices.Compact(set)
+
+		key := strings.Trim(fmt.Sprint(set), "[]")
+		if c, ok := classes[key]; ok {
+			c.queries++
+		} else {
+			classes[key] = &pointsToClass{pts: pts, labels: set, queries: 1}
+		}
+	}
+
+	// Classes are ordered by size to make the results deterministic.
+	cs := make([]*pointsToClass, 0, len(classes))
+	for _, c := range classes {
+		cs = append(cs, c)
+	}
+	slices.SortFunc(cs, func(a, b *pointsToClass) bool {
+		switch {
+		case a.queries != b.queries:
+			return a.queries > b.queries
+		case len(a.labels) != len(b.labels):
+			return len(a.labels) > len(b.labels)
+		}
+		return slices.Compare(a.labels, b.labels) < 0
+	})
+
+	index := make(map[int][]int)
+	for i, c := range cs {
+		for _, l := range c.labels {
+			index[l] = append(index[l], i)
+		}
+	}
+
+	a := &Aliasing{DistinctSets: len(cs)}
+	for i, c := range cs {
+		// Queries with identical non-empty sets alias each other.
+		a.MayAliasPairs += c.queries * (c.queries - 1) / 2
+
+		candidates := make(map[int]struct{})
+		for _, l := range c.labels {
+			for _, j := range index[l] {
+				if j > i {
+					candidates[j] = struct{}{}
+				}
+			}
+		}
+		for j := range candidates {
+			if c.pts.Intersects(cs[j].pts) {
+				a.MayAliasPairs += c.queries * cs[j].queries
+			}
+		}
+	}
+	if pairs := queries * (queries - 1) / 2; pairs > 0 {
+		a.Density = float64(a.MayAliasPairs) / float64(pairs)
+	}
+
+	for _, c := range cs {
+		if len(a.Classes) == largestAliasClasses {
+			break
+		}
+		a.Classes = append(a.Classes, AliasClass{Queries: c.queries, Size: len(c.labels)})
+	}
+
+	m.Aliasing = a
+	return m
+}
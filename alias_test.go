@@ -0,0 +1,61 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/pointer"
+)
+
+func TestAliasMetrics(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"lib": `package lib
+
+type T struct{ f *int }
+
+func Wrap(p *int) *T  { return &T{f: p} }
+func Unwrap(t *T) *int { return t.f }
+
+func Roundtrip() *int {
+	x := new(int)
+	return Unwrap(Wrap(x))
+}
+`,
+	}, "lib")
+
+	m := AnalyzeProgram(prog, ProgramOptions{
+		Scope:     ScopePackages,
+		Packages:  []string{"lib"},
+		Synthetic: true,
+	})
+	require.True(t, m.Ok())
+	require.Nil(t, m.Aliasing)
+
+	m = m.AliasMetrics()
+	require.NotNil(t, m.Aliasing)
+
+	// Compare against checking every pair of queries.
+	var pts []pointer.PointsToSet
+	for _, pt := range m.Payload.Queries {
+		pts = append(pts, pt.PointsTo())
+	}
+	pairs := 0
+	for i := range pts {
+		for j := i + 1; j < len(pts); j++ {
+			if pts[i].Intersects(pts[j]) {
+				pairs++
+			}
+		}
+	}
+	require.NotZero(t, pairs)
+	require.Equal(t, pairs, m.Aliasing.MayAliasPairs)
+	require.InDelta(t, float64(pairs)/float64(len(pts)*(len(pts)-1)/2), m.Aliasing.Density, 1e-9)
+
+	require.NotZero(t, m.Aliasing.DistinctSets)
+	require.NotEmpty(t, m.Aliasing.Classes)
+	require.LessOrEqual(t, len(m.Aliasing.Classes), m.Aliasing.DistinctSets)
+	for i := 1; i < len(m.Aliasing.Classes); i++ {
+		require.LessOrEqual(t, m.Aliasing.Classes[i].Queries, m.Aliasing.Classes[i-1].Queries)
+	}
+	require.Contains(t, m.String(), "- May-alias query pairs:")
+}
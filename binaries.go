@@ -0,0 +1,187 @@
+package stamets
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// ParallelOptions configures the concurrent analysis of the binaries of a program.
+type ParallelOptions struct {
+	// Maximum number of concurrent analyses. Defaults to GOMAXPROCS. Analyses run one
+	// at a time when measuring retained result sizes, as concurrent measurements overlap.
+	Workers int
+	// Heap size in bytes, above which no further analysis is started until a running
+	// analysis completes. At least one analysis is always running. 0 disables the limit.
+	MemoryLimit uint64
+}
+
+// BinaryMetrics aggregates the metrics of analyzing a single binary of a program.
+type BinaryMetrics struct {
+	// Path of the main package of the binary
+	Package string
+
+	PTA PTAMetrics
+}
+
+// BinariesMetrics aggregates the metrics of analyzing every binary of a program separately.
+type BinariesMetrics struct {
+	BaseMetrics[[]BinaryMetrics]
+
+	// Metrics merged over the successfully analyzed binaries. Counts are summed, and points-to set
+	// sizes are summarized over the pooled queries of all binaries, such that values shared by
+	// several binaries are counted once per binary. The duration is the wall-clock time of the
+	// whole analysis, and the merged metrics have no PTA result.
+	//
+	// The numbers of SSA functions and instructions, here and in the metrics of every binary,
+	// are the size of the whole program, not of the code reachable from a binary.
+	Summary PTAMetrics
+}
+
+func (m BinariesMetrics) String() string {
+	str := fmt.Sprintf(`
+BINARY METRICS
+- Duration: %f
+- Number of binaries: %d
+`,
+		m.Duration.Seconds(),
+		len(m.Payload),
+	)
+
+	for _, b := range m.Payload {
+		str += fmt.Sprintf("- Binary %s:\n", b.Package)
+		if _, err := b.PTA.Unpack(); err != nil {
+			str += fmt.Sprintf("\t- Error: %v\n", err)
+			continue
+		}
+		str += b.PTA.String()
+	}
+
+	return str + "- Merged metrics:\n" + m.Summary.String()
+}
+
+// AnalyzeBinaries runs the points-to analysis separately for every main package of an SSA program,
+// instead of a single analysis over all of them, collecting PTA metrics per binary and merged over
+// all binaries. If the options call for a synthetic main package, it is analyzed as the only binary.
+//
+// Analyses run concurrently, up to the worker limit. The memory limit delays starting analyses
+// while the heap is too large. Values are queried in the scope of the options once for all
+// binaries; the analysis of every binary only answers the queries in the code it reaches.
+func AnalyzeBinaries(prog *ssa.Program, opts ProgramOptions, popts ParallelOptions) BinariesMetrics {
+	start := time.Now()
+	mains, calls, err := programEntries(prog, opts)
+	if err != nil {
+		return BinariesMetrics{
+			BaseMetrics: BaseMetrics[[]BinaryMetrics]{
+				err: err,
+			},
+		}
+	}
+	slices.SortFunc(mains, func(a, b *ssa.Package) bool {
+		return a.Pkg.Path() < b.Pkg.Path()
+	})
+
+	workers := popts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if opts.MeasureRetained {
+		workers = 1
+	}
+
+	var mu sync.Mutex
+	scheduled := sync.NewCond(&mu)
+	running := 0
+	acquire := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		for running >= workers || (running > 0 && popts.MemoryLimit > 0 && heapAlloc() > popts.MemoryLimit) {
+			scheduled.Wait()
+		}
+		running++
+	}
+	release := func() {
+		mu.Lock()
+		running--
+		mu.Unlock()
+		scheduled.Broadcast()
+	}
+
+	analyze := opts.analyzer()
+	// Queries are shared by the configurations of all binaries, which the analyses do not modify.
+	queries := pointerConfig(prog, nil, opts)
+
+	bs := make([]BinaryMetrics, len(mains))
+	wg := &sync.WaitGroup{}
+	for i, main := range mains {
+		acquire()
+		wg.Add(1)
+		go func(i int, main *ssa.Package) {
+			defer wg.Done()
+			defer release()
+
+			config := *queries
+			config.Mains = []*ssa.Package{main}
+			pta := analyze(&config)
+			pta.SyntheticEntryCalls = calls
+			bs[i] = BinaryMetrics{Package: main.Pkg.Path(), PTA: pta}
+		}(i, main)
+	}
+	wg.Wait()
+
+	m := BinariesMetrics{
+		BaseMetrics: BaseMetrics[[]BinaryMetrics]{
+			Payload: bs,
+		},
+		Summary: mergeBinaries(prog, bs),
+	}
+	m.Summary.Functions, m.Summary.Instructions = programSize(prog)
+	m.Duration = time.Since(start)
+	m.Summary.Duration = m.Duration
+	return m
+}
+
+// AnalyzeBinariesWithTimeout runs the points-to analysis separately for every main package of an SSA
+// program like AnalyzeBinaries, in the alloted time limit.
+func AnalyzeBinariesWithTimeout(t time.Duration, prog *ssa.Program, opts ProgramOptions, popts ParallelOptions) (BinariesMetrics, bool) {
+	return TaskWithTimeout(t, func() BinariesMetrics {
+		return AnalyzeBinaries(prog, opts, popts)
+	})
+}
+
+// mergeBinaries merges the PTA metrics of the successfully analyzed binaries of a program,
+// except for the size of the program.
+func mergeBinaries(prog *ssa.Program, bs []BinaryMetrics) PTAMetrics {
+	var m PTAMetrics
+	var sizes, indirectSizes []int
+	warnings := &pointer.Result{}
+	for _, b := range bs {
+		res, err := b.PTA.Unpack()
+		if err != nil {
+			continue
+		}
+
+		m.Analysis = b.PTA.Analysis
+		m.Queries += b.PTA.Queries
+		m.IndirectQueries += b.PTA.IndirectQueries
+		m.SyntheticEntryCalls += b.PTA.SyntheticEntryCalls
+		m.RetainedBytes += b.PTA.RetainedBytes
+
+		sizes = append(sizes, b.PTA.sizes...)
+		indirectSizes = append(indirectSizes, b.PTA.indirectSizes...)
+		warnings.Warnings = append(warnings.Warnings, res.Warnings...)
+	}
+
+	m = m.withSizes(sizes, indirectSizes)
+
+	// Warnings are merged before summarizing them, as positions are only kept for the top warnings.
+	m.Payload = warnings
+	m = m.WarningMetrics(prog.Fset)
+	m.Payload = nil
+	return m
+}
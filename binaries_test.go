@@ -0,0 +1,72 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeBinaries(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"lib": `package lib
+
+type T struct{ f *int }
+
+func Id(t *T) *T { return t }
+`,
+		"cmd/a": `package main
+
+import "lib"
+
+func main() { lib.Id(&lib.T{}) }
+`,
+		"cmd/b": `package main
+
+import "lib"
+
+func main() { lib.Id(&lib.T{}) }
+`,
+	}, "lib", "cmd/b", "cmd/a")
+
+	// A single analysis conflates the allocations of both binaries.
+	all := AnalyzeProgram(prog, ProgramOptions{})
+	require.True(t, all.Ok())
+	require.Equal(t, 2, all.PointsToSetSizeMax)
+
+	for _, popts := range []ParallelOptions{{}, {Workers: 1}, {MemoryLimit: 1}} {
+		m := AnalyzeBinaries(prog, ProgramOptions{}, popts)
+		require.True(t, m.Ok())
+		require.Len(t, m.Payload, 2)
+		require.Equal(t, "cmd/a", m.Payload[0].Package)
+		require.Equal(t, "cmd/b", m.Payload[1].Package)
+
+		// Only values in functions reachable from the main package are queried.
+		queries := 0
+		for _, b := range m.Payload {
+			require.True(t, b.PTA.Ok())
+			require.Less(t, b.PTA.Queries, all.Queries)
+			require.Equal(t, 1, b.PTA.PointsToSetSizeMax)
+			queries += b.PTA.Queries
+		}
+
+		require.Nil(t, m.Summary.Payload)
+		require.Equal(t, queries, m.Summary.Queries)
+		require.Equal(t, 1, m.Summary.PointsToSetSizeMax)
+		require.Equal(t, all.Functions, m.Summary.Functions)
+		require.Contains(t, m.String(), "- Binary cmd/b:")
+	}
+
+	// Retained result sizes are measured one binary at a time.
+	m := AnalyzeBinaries(prog, ProgramOptions{MeasureRetained: true}, ParallelOptions{})
+	require.True(t, m.Ok())
+	require.Equal(t, m.Payload[0].PTA.RetainedBytes+m.Payload[1].PTA.RetainedBytes, m.Summary.RetainedBytes)
+
+	// Points-to set sizes are merged for the unification-based analysis.
+	m = AnalyzeBinaries(prog, ProgramOptions{Unification: true}, ParallelOptions{})
+	require.True(t, m.Ok())
+	require.NotZero(t, m.Summary.Queries)
+	require.Equal(t, 1, m.Summary.PointsToSetSizeMax)
+
+	m = AnalyzeBinaries(prog, ProgramOptions{Tests: true}, ParallelOptions{})
+	require.False(t, m.Ok())
+}
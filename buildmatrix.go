@@ -0,0 +1,261 @@
+package stamets
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// BuildConfiguration describes a target platform and a set of build tags.
+// Empty fields default to the ones of the package loading configuration.
+type BuildConfiguration struct {
+	GOOS   string
+	GOARCH string
+	Tags   []string
+}
+
+func (c BuildConfiguration) String() string {
+	str := c.GOOS + "/" + c.GOARCH
+	if str == "/" {
+		str = "default"
+	}
+	if len(c.Tags) > 0 {
+		str += " tags=" + strings.Join(c.Tags, ",")
+	}
+	return str
+}
+
+// apply produces a copy of the package loading configuration, updated with the platform
+// and build tags of the build configuration. Build tags are merged with the tags in the
+// build flags of the package loading configuration.
+func (c BuildConfiguration) apply(config *packages.Config) *packages.Config {
+	cfg := *config
+
+	env := config.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	// Later entries take precedence over earlier ones.
+	cfg.Env = slices.Clone(env)
+	if c.GOOS != "" {
+		cfg.Env = append(cfg.Env, "GOOS="+c.GOOS)
+	}
+	if c.GOARCH != "" {
+		cfg.Env = append(cfg.Env, "GOARCH="+c.GOARCH)
+	}
+
+	cfg.BuildFlags = slices.Clone(config.BuildFlags)
+	if len(c.Tags) > 0 {
+		flags, tags := splitTags(config.BuildFlags)
+		for _, tag := range c.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		cfg.BuildFlags = append(flags, "-tags="+strings.Join(tags, ","))
+	}
+
+	return &cfg
+}
+
+// splitTags separates the build tags set via -tags from the other build flags. Tags may be
+// given as the value of the flag, or as the next argument, separated by commas or spaces.
+func splitTags(buildFlags []string) (flags, tags []string) {
+	for i := 0; i < len(buildFlags); i++ {
+		flag := buildFlags[i]
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(flag, "-"), "=")
+		if name != "-tags" && name != "tags" {
+			flags = append(flags, flag)
+			continue
+		}
+		if !hasValue && i+1 < len(buildFlags) {
+			i++
+			value = buildFlags[i]
+		}
+		for _, tag := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return flags, tags
+}
+
+// BuildConfigurationMetrics aggregates the metrics of loading and analyzing
+// packages under a build configuration.
+type BuildConfigurationMetrics struct {
+	Configuration BuildConfiguration
+
+	Packages  PackagesMetrics
+	SSA       BaseMetrics[*ssa.Program]
+	CallGraph CallGraphMetrics
+
+	// Number of SSA functions and instructions
+	Functions    int
+	Instructions int
+}
+
+// BuildConfigurationDifference describes how the results under a build configuration
+// differ from the results under a baseline configuration.
+type BuildConfigurationDifference struct {
+	Baseline      BuildConfiguration
+	Configuration BuildConfiguration
+
+	// IDs of packages only loaded under the configuration
+	AddedPackages []string
+	// IDs of packages only loaded under the baseline
+	RemovedPackages []string
+
+	// Differences in the number of SSA functions and instructions
+	Functions    int
+	Instructions int
+	// Differences in the call graph metrics, including the number of functions
+	CallGraph CallGraphMetrics
+	// Difference in the number of call graph edges
+	CallGraphEdges int
+}
+
+// BuildMatrixMetrics aggregates the metrics of loading and analyzing the same packages
+// under different build configurations. The first configuration serves as the baseline
+// for differences.
+type BuildMatrixMetrics struct {
+	BaseMetrics[[]BuildConfigurationMetrics]
+
+	Differences []BuildConfigurationDifference
+}
+
+func (m BuildMatrixMetrics) String() string {
+	str := "\nBUILD MATRIX METRICS\n"
+	for _, c := range m.Payload {
+		if !c.Packages.Ok() {
+			_, err := c.Packages.Unpack()
+			str += fmt.Sprintf("- Configuration %s: failed to load packages: %v\n", c.Configuration, err)
+			continue
+		}
+		str += fmt.Sprintf("- Configuration %s: packages %d, SSA functions %d, SSA instructions %d, call graph functions %d\n",
+			c.Configuration,
+			c.Packages.Total().Packages,
+			c.Functions,
+			c.Instructions,
+			c.CallGraph.NumberOfFunctions(),
+		)
+	}
+
+	for _, d := range m.Differences {
+		str += fmt.Sprintf(`Differences of %s from %s:
+	- Added packages: %d
+	- Removed packages: %d
+	- SSA functions: %+d
+	- SSA instructions: %+d
+	- Call graph functions: %+d
+	- Call graph edges: %+d
+	- Out-degree P50, P90, P99, Max, Mode: %+d, %+d, %+d, %+d, %+d
+	- In-degree P50, P90, P99, Max, Mode: %+d, %+d, %+d, %+d, %+d
+`,
+			d.Configuration,
+			d.Baseline,
+			len(d.AddedPackages),
+			len(d.RemovedPackages),
+			d.Functions,
+			d.Instructions,
+			d.CallGraph.Functions,
+			d.CallGraphEdges,
+			d.CallGraph.OutDegreeP50,
+			d.CallGraph.OutDegreeP90,
+			d.CallGraph.OutDegreeP99,
+			d.CallGraph.OutDegreeMax,
+			d.CallGraph.OutDegreeMode,
+			d.CallGraph.InDegreeP50,
+			d.CallGraph.InDegreeP90,
+			d.CallGraph.InDegreeP99,
+			d.CallGraph.InDegreeMax,
+			d.CallGraph.InDegreeMode,
+		)
+	}
+
+	return str
+}
+
+// AnalyzeBuildMatrix loads the packages matching the query `patterns` under every build
+// configuration, builds the SSA program in the given mode, and constructs its call graph
+// via Class Hierarchy Analysis, rooted at the entry points of the program.
+func AnalyzeBuildMatrix(config *packages.Config, mode ssa.BuilderMode, configurations []BuildConfiguration, patterns ...string) BuildMatrixMetrics {
+	start := time.Now()
+
+	ms := make([]BuildConfigurationMetrics, 0, len(configurations))
+	for _, c := range configurations {
+		cm := BuildConfigurationMetrics{
+			Configuration: c,
+			Packages:      PackagesLoad(c.apply(config), patterns...),
+		}
+		if cm.Packages.Ok() {
+			cm.SSA = AllPackages(cm.Packages.Payload, mode)
+			cm.Functions, cm.Instructions = programSize(cm.SSA.Payload)
+			cm.CallGraph = GetCallGraphMetrics(chaCallGraph(cm.SSA.Payload))
+		}
+		ms = append(ms, cm)
+	}
+
+	m := BuildMatrixMetrics{
+		BaseMetrics: BaseMetrics[[]BuildConfigurationMetrics]{
+			Payload: ms,
+		},
+	}
+	if len(ms) > 0 && ms[0].Packages.Ok() {
+		for _, cm := range ms[1:] {
+			if cm.Packages.Ok() {
+				m.Differences = append(m.Differences, buildConfigurationDifference(ms[0], cm))
+			}
+		}
+	}
+	m.Duration = time.Since(start)
+
+	return m
+}
+
+// AnalyzeBuildMatrixWithTimeout loads and analyzes packages under every build configuration
+// like AnalyzeBuildMatrix, in the alloted time limit.
+func AnalyzeBuildMatrixWithTimeout(t time.Duration, config *packages.Config, mode ssa.BuilderMode, configurations []BuildConfiguration, patterns ...string) (BuildMatrixMetrics, bool) {
+	return TaskWithTimeout(t, func() BuildMatrixMetrics {
+		return AnalyzeBuildMatrix(config, mode, configurations, patterns...)
+	})
+}
+
+func buildConfigurationDifference(baseline, c BuildConfigurationMetrics) BuildConfigurationDifference {
+	ids := func(cm BuildConfigurationMetrics) map[string]struct{} {
+		res := make(map[string]struct{})
+		packages.Visit(cm.Packages.Payload, nil, func(pkg *packages.Package) {
+			res[pkg.ID] = struct{}{}
+		})
+		return res
+	}
+	difference := func(a, b map[string]struct{}) []string {
+		res := make(map[string]struct{})
+		for id := range a {
+			if _, ok := b[id]; !ok {
+				res[id] = struct{}{}
+			}
+		}
+		keys := maps.Keys(res)
+		slices.Sort(keys)
+		return keys
+	}
+	baselineIDs, cIDs := ids(baseline), ids(c)
+
+	return BuildConfigurationDifference{
+		Baseline:        baseline.Configuration,
+		Configuration:   c.Configuration,
+		AddedPackages:   difference(cIDs, baselineIDs),
+		RemovedPackages: difference(baselineIDs, cIDs),
+		Functions:       c.Functions - baseline.Functions,
+		Instructions:    c.Instructions - baseline.Instructions,
+		CallGraph:       c.CallGraph.difference(baseline.CallGraph),
+		CallGraphEdges:  c.CallGraph.NumberOfEdges() - baseline.CallGraph.NumberOfEdges(),
+	}
+}
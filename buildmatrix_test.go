@@ -0,0 +1,106 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestBuildConfigurationApply(t *testing.T) {
+	config := &packages.Config{
+		Env:        []string{"GOOS=darwin"},
+		BuildFlags: []string{"-race"},
+	}
+
+	c := BuildConfiguration{GOOS: "linux", GOARCH: "arm64", Tags: []string{"integration", "e2e"}}
+	require.Equal(t, "linux/arm64 tags=integration,e2e", c.String())
+
+	cfg := c.apply(config)
+	require.Equal(t, []string{"GOOS=darwin", "GOOS=linux", "GOARCH=arm64"}, cfg.Env)
+	require.Equal(t, []string{"-race", "-tags=integration,e2e"}, cfg.BuildFlags)
+
+	// Tags in the build flags are merged.
+	for _, flags := range [][]string{
+		{"-tags=netgo,e2e", "-race"},
+		{"-tags", "netgo e2e", "-race"},
+		{"--tags=netgo", "-race", "-tags=e2e"},
+	} {
+		cfg = c.apply(&packages.Config{BuildFlags: flags})
+		require.Equal(t, []string{"-race", "-tags=netgo,e2e,integration"}, cfg.BuildFlags)
+	}
+	// The original configuration is left untouched.
+	require.Equal(t, []string{"GOOS=darwin"}, config.Env)
+	require.Equal(t, []string{"-race"}, config.BuildFlags)
+
+	require.Equal(t, "default", BuildConfiguration{}.String())
+	require.Equal(t, config.BuildFlags, BuildConfiguration{}.apply(config).BuildFlags)
+}
+
+func TestBuildConfigurationDifference(t *testing.T) {
+	shared := &packages.Package{ID: "shared"}
+	linux := &packages.Package{ID: "linux", Imports: map[string]*packages.Package{"shared": shared}}
+	windows := &packages.Package{ID: "windows", Imports: map[string]*packages.Package{"shared": shared}}
+
+	baseline := BuildConfigurationMetrics{
+		Configuration: BuildConfiguration{GOOS: "linux"},
+		Packages: PackagesMetrics{
+			BaseMetrics: BaseMetrics[[]*packages.Package]{Payload: []*packages.Package{linux}},
+		},
+		Functions:    10,
+		Instructions: 100,
+		CallGraph:    CallGraphMetrics{Functions: 5, OutDegreeMax: 2, InDegreeP50: 1},
+	}
+	other := BuildConfigurationMetrics{
+		Configuration: BuildConfiguration{GOOS: "windows"},
+		Packages: PackagesMetrics{
+			BaseMetrics: BaseMetrics[[]*packages.Package]{Payload: []*packages.Package{windows}},
+		},
+		Functions:    12,
+		Instructions: 90,
+		CallGraph:    CallGraphMetrics{Functions: 7, OutDegreeMax: 5, InDegreeP50: 1},
+	}
+
+	d := buildConfigurationDifference(baseline, other)
+	require.Equal(t, []string{"windows"}, d.AddedPackages)
+	require.Equal(t, []string{"linux"}, d.RemovedPackages)
+	require.Equal(t, 2, d.Functions)
+	require.Equal(t, -10, d.Instructions)
+	require.Equal(t, 2, d.CallGraph.Functions)
+	require.Equal(t, 3, d.CallGraph.OutDegreeMax)
+	require.Zero(t, d.CallGraph.InDegreeP50)
+	require.Zero(t, d.CallGraphEdges)
+}
+
+func TestAnalyzeBuildMatrix(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"go.mod":         "module example.com/m\n\ngo 1.20\n",
+		"main.go":        "package main\n\nfunc main() { run() }\n",
+		"run_default.go": "//go:build !extra\n\npackage main\n\nfunc run() {}\n",
+		"run_extra.go":   "//go:build extra\n\npackage main\n\nimport \"example.com/m/extra\"\n\nfunc run() { extra.A(); extra.B() }\n",
+		"extra/extra.go": "package extra\n\nfunc A() {}\n\nfunc B() { A() }\n",
+	})
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Dir: dir}
+
+	m := AnalyzeBuildMatrix(config, 0, []BuildConfiguration{{}, {Tags: []string{"extra"}}}, ".")
+	require.Len(t, m.Payload, 2)
+	for _, c := range m.Payload {
+		require.True(t, c.Packages.Ok())
+		require.True(t, c.SSA.Ok())
+	}
+
+	// Edges from the root do not count as a call site.
+	baseline := m.Payload[0].CallGraph
+	require.Equal(t, 1, baseline.OutDegreeMax)
+
+	require.Len(t, m.Differences, 1)
+	d := m.Differences[0]
+	require.Equal(t, BuildConfiguration{}, d.Baseline)
+	require.Equal(t, []string{"extra"}, d.Configuration.Tags)
+	require.Equal(t, []string{"example.com/m/extra"}, d.AddedPackages)
+	require.Empty(t, d.RemovedPackages)
+	require.Positive(t, d.Functions)
+	require.Positive(t, d.Instructions)
+	require.Positive(t, d.CallGraphEdges)
+	require.Contains(t, m.String(), "Differences of default tags=extra from default")
+}
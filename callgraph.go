@@ -5,7 +5,10 @@ import (
 
 	"golang.org/x/exp/slices"
 	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/vta"
 	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
 )
 
 // CallGraphMetrics encodes metrics about call graphs e.g.
@@ -77,7 +80,8 @@ func GetCallGraphMetrics(cg *callgraph.Graph) CallGraphMetrics {
 
 // CallGraphOutDegreeMetrics computes out-degree metrics on the call graph. The out degree
 // is computed per-call site. Every function without outgoing calls contributes with a 0
-// to the statistics.
+// to the statistics. Edges without a call site e.g., from the root to the entry points
+// of the program, are ignored.
 func (m CallGraphMetrics) CallGraphOutDegreeMetrics() CallGraphMetrics {
 	if m.Payload == nil {
 		return m
@@ -96,7 +100,9 @@ func (m CallGraphMetrics) CallGraphOutDegreeMetrics() CallGraphMetrics {
 		}
 
 		for _, e := range n.Out {
-			outs[e.Site]++
+			if e.Site != nil {
+				outs[e.Site]++
+			}
 		}
 
 		for _, count := range outs {
@@ -144,6 +150,24 @@ func (m CallGraphMetrics) CallGraphInDegreeMetrics() CallGraphMetrics {
 	return m
 }
 
+// difference subtracts the metrics of another call graph from the metrics of this call graph.
+// The difference has no call graph.
+func (m CallGraphMetrics) difference(o CallGraphMetrics) CallGraphMetrics {
+	return CallGraphMetrics{
+		Functions:     m.NumberOfFunctions() - o.NumberOfFunctions(),
+		OutDegreeMax:  m.OutDegreeMax - o.OutDegreeMax,
+		OutDegreeP50:  m.OutDegreeP50 - o.OutDegreeP50,
+		OutDegreeP90:  m.OutDegreeP90 - o.OutDegreeP90,
+		OutDegreeP99:  m.OutDegreeP99 - o.OutDegreeP99,
+		OutDegreeMode: m.OutDegreeMode - o.OutDegreeMode,
+		InDegreeMax:   m.InDegreeMax - o.InDegreeMax,
+		InDegreeP50:   m.InDegreeP50 - o.InDegreeP50,
+		InDegreeP90:   m.InDegreeP90 - o.InDegreeP90,
+		InDegreeP99:   m.InDegreeP99 - o.InDegreeP99,
+		InDegreeMode:  m.InDegreeMode - o.InDegreeMode,
+	}
+}
+
 // NumberOfFunctions produces the number of functions in the call-graph produced
 // by the Points-To analysis.
 func (m CallGraphMetrics) NumberOfFunctions() int {
@@ -153,3 +177,66 @@ func (m CallGraphMetrics) NumberOfFunctions() int {
 
 	return len(m.Payload.Nodes)
 }
+
+// NumberOfEdges produces the number of call edges in the call graph.
+func (m CallGraphMetrics) NumberOfEdges() (edges int) {
+	if m.Payload == nil {
+		return 0
+	}
+
+	for _, n := range m.Payload.Nodes {
+		edges += len(n.Out)
+	}
+	return edges
+}
+
+// rootFunctions returns the entry points of a program: the initializer and main functions
+// of its main packages, or the initializers of all its packages if it has no main packages.
+// Synthetic main packages, left in the program by earlier analyses, are excluded.
+func rootFunctions(prog *ssa.Program) (roots []*ssa.Function) {
+	var all []*ssa.Package
+	for _, pkg := range prog.AllPackages() {
+		if !isSynthetic(pkg) {
+			all = append(all, pkg)
+		}
+	}
+	pkgs := ssautil.MainPackages(all)
+	if len(pkgs) == 0 {
+		pkgs = all
+	}
+	for _, pkg := range pkgs {
+		for _, name := range []string{"init", "main"} {
+			if fn := pkg.Func(name); fn != nil {
+				roots = append(roots, fn)
+			}
+		}
+	}
+	return roots
+}
+
+// chaCallGraph constructs the call graph of a program via Class Hierarchy Analysis.
+// Unlike cha.CallGraph, the root of the call graph calls the entry points of the program,
+// such that the call graph is amenable to CallGraphMetrics.
+func chaCallGraph(prog *ssa.Program) *callgraph.Graph {
+	cg := cha.CallGraph(prog)
+	addRootEdges(cg, prog)
+	return cg
+}
+
+// vtaCallGraph constructs the call graph of a program via Variable Type Analysis, refining
+// the call graph constructed via Class Hierarchy Analysis. Like chaCallGraph, the root of the
+// call graph calls the entry points of the program.
+func vtaCallGraph(prog *ssa.Program) *callgraph.Graph {
+	cg := vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog))
+	// Call graphs constructed via VTA are not rooted.
+	cg.Root = cg.CreateNode(nil)
+	addRootEdges(cg, prog)
+	return cg
+}
+
+// addRootEdges adds edges from the root of the call graph to the entry points of the program.
+func addRootEdges(cg *callgraph.Graph, prog *ssa.Program) {
+	for _, fn := range rootFunctions(prog) {
+		callgraph.AddEdge(cg.Root, nil, cg.CreateNode(fn))
+	}
+}
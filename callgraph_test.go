@@ -122,6 +122,16 @@ func TestCallGraphOutDegreeMetrics(t *testing.T) {
 	require.Equal(t, 1, m.OutDegreeP50)
 	require.Equal(t, 2, m.OutDegreeP90)
 	require.Equal(t, 2, m.OutDegreeP99)
+
+	// Edges without call sites, like from the root of the call graph, are not counted.
+	for _, n := range cg.Nodes {
+		if n != cg.Root {
+			callgraph.AddEdge(cg.Root, nil, n)
+		}
+	}
+	m = m.CallGraphOutDegreeMetrics()
+	require.Equal(t, 2, m.OutDegreeMax)
+	require.Equal(t, 1, m.OutDegreeP50)
 }
 
 func TestCallGraphInDegreeMetrics(t *testing.T) {
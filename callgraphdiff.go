@@ -0,0 +1,148 @@
+package stamets
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+// MissingEdge is a call graph edge which is missing from another call graph.
+type MissingEdge struct {
+	Caller, Callee *ssa.Function
+	Site           ssa.CallInstruction
+	Position       token.Position
+
+	// Node IDs of the caller and callee in the call graph including the edge
+	callerID, calleeID int
+}
+
+func (e MissingEdge) String() string {
+	return fmt.Sprintf("%s: %s -> %s", e.Position, e.Caller, e.Callee)
+}
+
+// CallGraphComparisonMetrics lists the edges of a call graph which are missing from another
+// call graph of the same program, ordered by call site position.
+type CallGraphComparisonMetrics struct {
+	BaseMetrics[[]MissingEdge]
+
+	// Number of distinct edges in the compared call graph
+	Edges int
+	// Number of distinct edges missing from the other call graph
+	Missing int
+	// Number of call sites with missing edges
+	Sites int
+	// Number of callers with missing edges
+	Callers int
+}
+
+func (m CallGraphComparisonMetrics) String() string {
+	str := fmt.Sprintf(`
+CALL GRAPH COMPARISON
+- Number of edges: %d
+- Missing edges: %d
+- Call sites with missing edges: %d
+- Callers with missing edges: %d
+Missing edges:
+`,
+		m.Edges,
+		m.Missing,
+		m.Sites,
+		m.Callers,
+	)
+	for _, e := range m.Payload {
+		str += "\t- " + e.String() + "\n"
+	}
+	return str
+}
+
+// edgeKey identifies a call graph edge by its caller, call site position and callee,
+// such that edges are comparable across call graphs of the same program.
+type edgeKey struct {
+	caller *ssa.Function
+	pos    token.Pos
+	callee *ssa.Function
+}
+
+// callGraphEdges collects the distinct edges of a call graph, excluding the edges from its root.
+// Call graph algorithms disagree on the entry points of a program, which are called from the root.
+func callGraphEdges(cg *callgraph.Graph) map[edgeKey]*callgraph.Edge {
+	edges := make(map[edgeKey]*callgraph.Edge)
+	for fn, n := range cg.Nodes {
+		if fn == nil || n == cg.Root {
+			continue
+		}
+		for _, e := range n.Out {
+			key := edgeKey{caller: fn, callee: e.Callee.Func}
+			if e.Site != nil {
+				key.pos = e.Site.Pos()
+			}
+			if _, ok := edges[key]; !ok {
+				edges[key] = e
+			}
+		}
+	}
+	return edges
+}
+
+// CompareCallGraphs reports the edges of call graph a which are missing from call graph b.
+// Both call graphs must be constructed for the same program. Edges are keyed by caller, call site
+// position and callee, and edges from the root are ignored. Since more precise call graph algorithms
+// produce subsets of the call graphs of less precise ones e.g., a PTA call graph is a subset of the CHA
+// call graph, edges missing from the less precise call graph indicate unsoundness in either algorithm.
+func CompareCallGraphs(a, b *callgraph.Graph) CallGraphComparisonMetrics {
+	if a == nil || b == nil {
+		return CallGraphComparisonMetrics{
+			BaseMetrics: BaseMetrics[[]MissingEdge]{
+				err: errors.New("comparing call graphs requires both call graphs"),
+			},
+		}
+	}
+
+	edges := callGraphEdges(a)
+	other := callGraphEdges(b)
+
+	m := CallGraphComparisonMetrics{Edges: len(edges)}
+	sites := make(map[ssa.CallInstruction]struct{})
+	callers := make(map[*ssa.Function]struct{})
+	for key, e := range edges {
+		if _, ok := other[key]; ok {
+			continue
+		}
+
+		var pos token.Position
+		if key.pos.IsValid() && key.caller.Prog != nil {
+			pos = key.caller.Prog.Fset.Position(key.pos)
+		}
+		m.Payload = append(m.Payload, MissingEdge{
+			Caller:   key.caller,
+			Callee:   key.callee,
+			Site:     e.Site,
+			Position: pos,
+			callerID: e.Caller.ID,
+			calleeID: e.Callee.ID,
+		})
+		if e.Site != nil {
+			sites[e.Site] = struct{}{}
+		}
+		callers[key.caller] = struct{}{}
+	}
+
+	slices.SortFunc(m.Payload, func(a, b MissingEdge) bool {
+		switch {
+		case a.Position != b.Position:
+			return positionLess(a.Position, b.Position)
+		case a.callerID != b.callerID:
+			return a.callerID < b.callerID
+		}
+		return a.calleeID < b.calleeID
+	})
+
+	m.Missing = len(m.Payload)
+	m.Sites = len(sites)
+	m.Callers = len(callers)
+	return m
+}
@@ -0,0 +1,83 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+)
+
+func TestCompareCallGraphs(t *testing.T) {
+	require.False(t, CompareCallGraphs(nil, &callgraph.Graph{}).Ok())
+
+	a, nodes := makeCallgraph(t)
+
+	// Keep the edges of a to 3, and from 4 to 1 and 1 to 2.
+	b := callgraph.New(nodes[0].Func)
+	for _, n := range nodes {
+		for _, e := range n.Out {
+			if e.Callee.ID == 3 || e.Caller.ID == 4 || e.Caller.ID == 1 {
+				callgraph.AddEdge(b.CreateNode(e.Caller.Func), e.Site, b.CreateNode(e.Callee.Func))
+			}
+		}
+	}
+
+	m := CompareCallGraphs(a, a)
+	require.True(t, m.Ok())
+	require.Equal(t, 6, m.Edges)
+	require.Zero(t, m.Missing)
+
+	m = CompareCallGraphs(a, b)
+	require.True(t, m.Ok())
+	// Edges from the root are ignored.
+	require.Equal(t, 6, m.Edges)
+	require.Equal(t, 3, m.Missing)
+	require.Equal(t, 2, m.Sites)
+	require.Equal(t, 1, m.Callers)
+	for i, callee := range []int{1, 5, 6} {
+		require.Equal(t, nodes[2].Func, m.Payload[i].Caller)
+		require.Equal(t, nodes[callee].Func, m.Payload[i].Callee)
+	}
+
+	m = CompareCallGraphs(b, a)
+	require.Equal(t, 3, m.Edges)
+	require.Zero(t, m.Missing)
+}
+
+func TestCompareCallGraphsPTA(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"main": `package main
+
+type I interface{ M() }
+
+type A struct{}
+
+func (A) M() {}
+
+type B struct{}
+
+func (B) M() {}
+
+func main() {
+	var i I = A{}
+	i.M()
+}
+`,
+	}, "main")
+
+	m := AnalyzeProgram(prog, ProgramOptions{BuildCallGraph: true})
+	require.True(t, m.Ok())
+	pta := m.Payload.CallGraph
+	cg := cha.CallGraph(prog)
+
+	require.Zero(t, CompareCallGraphs(pta, cg).Missing)
+
+	// Class Hierarchy Analysis includes the methods of B, *A and *B.
+	c := CompareCallGraphs(cg, pta)
+	require.True(t, c.Ok())
+	require.Equal(t, 3, c.Missing)
+	require.Equal(t, 1, c.Sites)
+	require.Equal(t, "main.go", c.Payload[0].Position.Filename)
+	require.Contains(t, c.String(), "- Missing edges: 3")
+}
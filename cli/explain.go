@@ -0,0 +1,75 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/vladsaiocuber/stamets"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// explain loads the packages matching the query patterns, runs the points-to analysis, and
+// reports the queries with the largest points-to sets, with their source positions.
+func explain(args []string) {
+	var dir, module, pkgs string
+	var n, samples int
+	var tests, synthetic, reflection bool
+	fs := flag.NewFlagSet("explain", flag.ExitOnError)
+	fs.StringVar(&dir, "dir", os.Getenv("PWD"), "Directory in which to load packages.")
+	fs.IntVar(&n, "n", 10, "Number of reported queries.")
+	fs.IntVar(&samples, "labels", 5, "Number of sampled labels per query.")
+	fs.StringVar(&module, "module", "", "Only query values in the packages of this module.")
+	fs.StringVar(&pkgs, "packages", "", "Only query values in these comma-separated packages.")
+	fs.BoolVar(&tests, "tests", false, "Analyze test main packages.")
+	fs.BoolVar(&synthetic, "synthetic", false, "Analyze from a synthetic main package calling every exported function in scope.")
+	fs.BoolVar(&reflection, "reflection", false, "Handle reflection soundly.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stamets explain [flags] patterns...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	opts := stamets.ProgramOptions{
+		Tests:      tests,
+		Synthetic:  synthetic,
+		Reflection: reflection,
+	}
+	switch {
+	case pkgs != "":
+		opts.Scope = stamets.ScopePackages
+		opts.Packages = strings.Split(pkgs, ",")
+	case module != "":
+		opts.Scope = stamets.ScopeMainModule
+		opts.Module = module
+	}
+
+	lm := stamets.PackagesLoad(&packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Dir:   dir,
+		Tests: tests,
+	}, fs.Args()...)
+	loaded, err := lm.Unpack()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	prog, _ := stamets.AllPackages(loaded, ssa.InstantiateGenerics).Unpack()
+	m := stamets.AnalyzeProgram(prog, opts)
+	if _, err := m.Unpack(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	qs, err := m.ImpreciseQueries(prog, n, samples)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Print(m)
+	fmt.Print(qs)
+}
@@ -11,11 +11,21 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "explain" {
+		explain(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "predict" {
+		predict(os.Args[2:])
+		return
+	}
+
 	var dir string
-	var pta, cg bool
+	var pta, cg, cost bool
 	flag.StringVar(&dir, "dir", os.Getenv("PWD"), "Target directory.")
 	flag.BoolVar(&pta, "pta", false, "Aggregate PTA results.")
 	flag.BoolVar(&cg, "cg", false, "Aggregate call graph results.")
+	flag.BoolVar(&cost, "cost", false, "Fit a cost model predicting PTA duration and memory on PTA results, to be used by the predict subcommand.")
 	flag.Parse()
 
 	if pta {
@@ -46,6 +56,40 @@ func main() {
 			stamets.MakeSeries(func(m stamets.PTAMetrics) int {
 				return m.PointsToSetSizeMax
 			}, ptas...))
+		PrintSeries(
+			"PTA P50 indirect points-to-set size",
+			stamets.MakeSeries(func(m stamets.PTAMetrics) int {
+				return m.IndirectPointsToSetSizeP50
+			}, ptas...))
+		PrintSeries(
+			"PTA P90 indirect points-to-set size",
+			stamets.MakeSeries(func(m stamets.PTAMetrics) int {
+				return m.IndirectPointsToSetSizeP90
+			}, ptas...))
+		PrintSeries(
+			"PTA P99 indirect points-to-set size",
+			stamets.MakeSeries(func(m stamets.PTAMetrics) int {
+				return m.IndirectPointsToSetSizeP99
+			}, ptas...))
+		PrintSeries(
+			"PTA Max indirect points-to-set size",
+			stamets.MakeSeries(func(m stamets.PTAMetrics) int {
+				return m.IndirectPointsToSetSizeMax
+			}, ptas...))
+		PrintSeries(
+			"PTA number of warnings",
+			stamets.MakeSeries(func(m stamets.PTAMetrics) int {
+				return m.Warnings
+			}, ptas...))
+	}
+
+	if cost {
+		model, err := stamets.FitCostModel(stamets.AggregatePTAResults(dir))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Print(model)
 	}
 
 	if cg {
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/vladsaiocuber/stamets"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+)
+
+// predict loads a cost model printed by the -cost mode and the packages matching the query
+// patterns, and reports the predicted cost of the points-to analysis at every degradation
+// level, and the least degraded level predicted to complete in the time budget. If requested,
+// the points-to analysis is then run adaptively, skipping levels predicted to exceed the budget.
+func predict(args []string) {
+	var dir, model, module, pkgs string
+	var budget time.Duration
+	var tests, synthetic, reflection, run bool
+	fs := flag.NewFlagSet("predict", flag.ExitOnError)
+	fs.StringVar(&dir, "dir", os.Getenv("PWD"), "Directory in which to load packages.")
+	fs.StringVar(&model, "model", "", "File containing a cost model printed by the -cost mode.")
+	fs.DurationVar(&budget, "budget", time.Minute, "Time budget of the points-to analysis.")
+	fs.BoolVar(&run, "run", false, "Run the points-to analysis, skipping degradation levels predicted to exceed the budget.")
+	fs.StringVar(&module, "module", "", "Only query values in the packages of this module.")
+	fs.StringVar(&pkgs, "packages", "", "Only query values in these comma-separated packages.")
+	fs.BoolVar(&tests, "tests", false, "Analyze test main packages.")
+	fs.BoolVar(&synthetic, "synthetic", false, "Analyze from a synthetic main package calling every exported function in scope.")
+	fs.BoolVar(&reflection, "reflection", false, "Handle reflection soundly.")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: stamets predict -model file [flags] patterns...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	f, err := os.Open(model)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	c, err := stamets.UnparseCostModelFromReader(f)
+	f.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	opts := stamets.ProgramOptions{
+		Tests:      tests,
+		Synthetic:  synthetic,
+		Reflection: reflection,
+	}
+	switch {
+	case pkgs != "":
+		opts.Scope = stamets.ScopePackages
+		opts.Packages = strings.Split(pkgs, ",")
+	case module != "":
+		opts.Scope = stamets.ScopeMainModule
+		opts.Module = module
+	}
+
+	lm := stamets.PackagesLoad(&packages.Config{
+		Mode:  packages.LoadAllSyntax,
+		Dir:   dir,
+		Tests: tests,
+	}, fs.Args()...)
+	loaded, err := lm.Unpack()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	prog, _ := stamets.AllPackages(loaded, ssa.InstantiateGenerics).Unpack()
+	ps, err := c.PredictProgram(prog, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Println("\nCOST PREDICTIONS")
+	for _, p := range ps {
+		fmt.Printf("- Degradation level %s: %d queries, %d indirect queries, predicted duration %s, retained result size %d bytes\n",
+			p.Level, p.Queries, p.IndirectQueries, p.Duration, p.RetainedBytes)
+	}
+	p, ok, _ := c.Degrade(prog, opts, budget)
+	if ok {
+		fmt.Printf("- Least degraded level within %s: %s\n", budget, p.Level)
+	} else {
+		fmt.Printf("- Every level is predicted to exceed %s\n", budget)
+	}
+
+	if run {
+		fmt.Print(stamets.AnalyzePredicted(prog, opts, budget, c))
+	}
+}
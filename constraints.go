@@ -0,0 +1,282 @@
+package stamets
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/pointer"
+)
+
+// constraintKinds lists the kinds of constraints of the points-to analysis,
+// in the order they are reported.
+var constraintKinds = []string{
+	"addr",
+	"copy",
+	"load",
+	"store",
+	"offsetAddr",
+	"typeFilter",
+	"untag",
+	"invoke",
+	"intrinsic",
+}
+
+// heaviestConstraintPackages is the number of packages with the most
+// constraints reported in constraint metrics.
+const heaviestConstraintPackages = 10
+
+var (
+	// constraintLine matches constraints in the pointer analysis log.
+	constraintLine = regexp.MustCompile(`^\t(` + strings.Join(constraintKinds[:len(constraintKinds)-1], "|") + `) n\d+`)
+	// intrinsicLine matches constraints modelling intrinsics e.g., reflection,
+	// in the pointer analysis log.
+	intrinsicLine = regexp.MustCompile(`^\t(n\d+ = )?(reflect[ .]|\(\*reflect\.rtype\)\.|runtime\.SetFinalizer\(|time\.startTimer\()`)
+)
+
+// constraintKind classifies a line of the pointer analysis log as a constraint
+// of the given kind, or returns the empty string.
+func constraintKind(l string) string {
+	if match := constraintLine.FindStringSubmatch(l); match != nil {
+		return match[1]
+	}
+	if intrinsicLine.MatchString(l) {
+		return "intrinsic"
+	}
+	return ""
+}
+
+// Log sections of the pointer analysis, in order.
+const (
+	logGenerate = iota
+	logOptimize
+	logSolve
+)
+
+// ConstraintMetrics encodes metrics about the constraint system of the points-to
+// analysis, as parsed from the analysis log.
+type ConstraintMetrics struct {
+	// Number of nodes and constraints generated offline
+	Nodes       int
+	Constraints int
+	// Number of nodes, constraints and distinct points-to sets
+	// after offline optimization
+	OptimizedNodes       int
+	OptimizedConstraints int
+	PointsToSets         int
+
+	// Number of constraints generated offline, by kind. Constraints modelling
+	// intrinsics e.g., reflection, are of the "intrinsic" kind.
+	Kinds map[string]int
+	// Number of constraints generated online by the solver
+	// e.g., for dynamic calls and reflection
+	OnlineConstraints int
+
+	// Number of nodes whose points-to sets were found equivalent to the points-to
+	// set of a canonical node by the offline pointer equivalence optimization (HVN)
+	Collapsed int
+	// Number of canonical nodes found by HVN
+	Canonical int
+
+	// Number of constraints generated offline for the functions of every package
+	Packages map[string]int
+}
+
+func (m ConstraintMetrics) String() string {
+	str := fmt.Sprintf(`Constraint system metrics:
+	- Number of nodes: %d
+	- Number of constraints: %d
+	- Number of online constraints: %d
+	- Number of nodes after optimization: %d
+	- Number of constraints after optimization: %d
+	- Number of points-to sets after optimization: %d
+	- Number of nodes collapsed by HVN: %d
+	- Number of canonical nodes after HVN: %d
+`,
+		m.Nodes,
+		m.Constraints,
+		m.OnlineConstraints,
+		m.OptimizedNodes,
+		m.OptimizedConstraints,
+		m.PointsToSets,
+		m.Collapsed,
+		m.Canonical,
+	)
+
+	str += "\tConstraints by kind:\n"
+	for _, kind := range constraintKinds {
+		if n, ok := m.Kinds[kind]; ok {
+			str += fmt.Sprintf("\t\t- %s: %d\n", kind, n)
+		}
+	}
+
+	pkgs := maps.Keys(m.Packages)
+	slices.SortFunc(pkgs, func(a, b string) bool {
+		if m.Packages[a] != m.Packages[b] {
+			return m.Packages[a] > m.Packages[b]
+		}
+		return a < b
+	})
+	if len(pkgs) > heaviestConstraintPackages {
+		pkgs = pkgs[:heaviestConstraintPackages]
+	}
+	str += "\tPackages with the most constraints:\n"
+	for _, pkg := range pkgs {
+		str += fmt.Sprintf("\t\t- %s: %d\n", pkg, m.Packages[pkg])
+	}
+
+	return str
+}
+
+// ParseConstraintLog parses the log of the points-to analysis, as written to pointer.Config.Log,
+// and collects metrics about the constraint system.
+func ParseConstraintLog(r io.Reader) ConstraintMetrics {
+	m := ConstraintMetrics{
+		Kinds:    make(map[string]int),
+		Packages: make(map[string]int),
+	}
+
+	const (
+		GENERATE = "==== Generating constraints for "
+		OPTIMIZE = "==== Pointer equivalence optimization"
+		RENUMBER = "==== Renumbering"
+		SOLVE    = "==== Solving constraints"
+
+		CONSTRAINTS = "# constraints:"
+		NODES       = "# nodes:"
+		PTSETS      = "# ptsets:"
+
+		COLLAPSED = "\tpts(n"
+		CANONICAL = ") is canonical"
+	)
+
+	// Counts are logged once after generating constraints, and once after optimization.
+	counts := 0
+	section, pkg := logGenerate, syntheticPackage
+	br := bufio.NewReader(r)
+	for {
+		l, err := br.ReadString('\n')
+		l = strings.TrimSuffix(l, "\n")
+
+		switch kind := constraintKind(l); {
+		case strings.HasPrefix(l, GENERATE):
+			section = logGenerate
+			pkg = constraintLogPackage(strings.TrimPrefix(l, GENERATE))
+		case strings.HasPrefix(l, OPTIMIZE), strings.HasPrefix(l, RENUMBER):
+			section = logOptimize
+		case strings.HasPrefix(l, SOLVE):
+			section = logSolve
+		case kind != "":
+			if section == logSolve {
+				m.OnlineConstraints++
+				break
+			}
+			m.Kinds[kind]++
+			m.Packages[pkg]++
+		case strings.HasPrefix(l, COLLAPSED):
+			if strings.Contains(l, CANONICAL) {
+				m.Canonical++
+			} else {
+				m.Collapsed++
+			}
+		case strings.HasPrefix(l, CONSTRAINTS):
+			counts++
+			if v, err := strconv.Atoi(getRowValue(CONSTRAINTS, l)); err == nil {
+				if counts == 1 {
+					m.Constraints = v
+				} else {
+					m.OptimizedConstraints = v
+				}
+			}
+		case strings.HasPrefix(l, NODES):
+			if v, err := strconv.Atoi(getRowValue(NODES, l)); err == nil {
+				if counts == 1 {
+					m.Nodes = v
+				} else {
+					m.OptimizedNodes = v
+				}
+			}
+		case strings.HasPrefix(l, PTSETS):
+			if v, err := strconv.Atoi(getRowValue(PTSETS, l)); err == nil && counts > 1 {
+				m.PointsToSets = v
+			}
+		}
+
+		if err != nil {
+			break
+		}
+	}
+
+	return m
+}
+
+// constraintLogPackage extracts the package path of the function in the header
+// of a constraint generation section e.g., "cg12:(*example.com/pkg.T).M, shared contour".
+// Functions without a package are attributed to the synthetic package.
+func constraintLogPackage(header string) string {
+	fn := header
+	if i := strings.Index(fn, ":"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	for _, contour := range []string{", shared contour", ", as called from "} {
+		if i := strings.Index(fn, contour); i >= 0 {
+			fn = fn[:i]
+		}
+	}
+
+	fn = strings.TrimLeft(fn, "(*")
+	if i := strings.IndexAny(fn, ")[$"); i >= 0 {
+		fn = fn[:i]
+	}
+	// Synthetic package paths may be suffixed to distinguish synthetic main packages.
+	if strings.HasPrefix(fn, syntheticPath+".") || strings.HasPrefix(fn, syntheticPath+"/") {
+		return syntheticPath
+	}
+	// Package paths may contain dots before the last slash.
+	slash := strings.LastIndex(fn, "/")
+	if i := strings.Index(fn[slash+1:], "."); i >= 0 {
+		return fn[:slash+1+i]
+	}
+	return syntheticPackage
+}
+
+// AnalyzeConstraints runs the points-to analysis like Analyze, additionally collecting
+// metrics about the constraint system from the analysis log. If the configuration already
+// has a log, it still receives the analysis log. Logging significantly slows down the
+// analysis, which is included in its duration.
+func AnalyzeConstraints(config *pointer.Config) PTAMetrics {
+	cfg := *config
+	pr, pw := io.Pipe()
+	if config.Log != nil {
+		cfg.Log = io.MultiWriter(pw, config.Log)
+	} else {
+		cfg.Log = pw
+	}
+
+	parsed := make(chan ConstraintMetrics)
+	go func() {
+		parsed <- ParseConstraintLog(pr)
+	}()
+
+	m := Analyze(&cfg)
+	pw.Close()
+	cm := <-parsed
+	if m.Ok() {
+		m.Constraints = &cm
+	}
+	return m
+}
+
+// AnalyzeConstraintsWithTimeout runs the points-to analysis and collects metrics about the
+// constraint system like AnalyzeConstraints, in the alloted time limit.
+func AnalyzeConstraintsWithTimeout(t time.Duration, config *pointer.Config) (PTAMetrics, bool) {
+	return TaskWithTimeout(t, func() PTAMetrics {
+		return AnalyzeConstraints(config)
+	})
+}
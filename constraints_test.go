@@ -0,0 +1,111 @@
+package stamets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintLogPackage(t *testing.T) {
+	require.Equal(t, "example.com/pkg", constraintLogPackage("cg12:example.com/pkg.F, shared contour"))
+	require.Equal(t, "example.com/pkg", constraintLogPackage("cg12:(*example.com/pkg.T).M, as called from example.com/pkg.F"))
+	require.Equal(t, "example.com/pkg", constraintLogPackage("cg12:example.com/pkg.F$1, shared contour"))
+	require.Equal(t, "example.com/pkg", constraintLogPackage("cg12:example.com/pkg.G[int, string], shared contour"))
+	require.Equal(t, "fmt", constraintLogPackage("cg3:fmt.Println, shared contour"))
+	require.Equal(t, syntheticPath, constraintLogPackage("cg2:"+syntheticPath+".main, shared contour"))
+	require.Equal(t, syntheticPath, constraintLogPackage("cg2:"+syntheticPath+"/1f.main, shared contour"))
+	require.Equal(t, syntheticPackage, constraintLogPackage("cg1:<root>, shared contour"))
+}
+
+func TestParseConstraintLog(t *testing.T) {
+	m := ParseConstraintLog(strings.NewReader(`==== Generating constraints
+	create n1 invalid type for (zero)
+	addr n2 <- {&n3}
+
+
+==== Generating constraints for cg4:example.com/pkg.F, shared contour
+func F():
+	t0 = new int (x)
+	create n5 *int for t0
+	copy n6 <- n5
+	load n7 <- n6[0]
+	store n6[0] <- n7
+	offsetAddr n8 <- n6.#1
+	typeFilter n9 <- n6.(*int)
+	untag n10 <- n6.(int)
+	invoke n11.String(n12 ...)
+	n13 = reflect.TypeOf(n11)
+	runtime.SetFinalizer(n6, n11)
+# constraints:	10
+	      1  (11%)	*pointer.addrConstraint
+# nodes:	12
+# ptsets:	12
+
+
+==== Pointer equivalence optimization
+
+	pts(n5) is canonical : 	(*int)
+	pts(n6) = pts(n5) : *int
+	pts(n7) = pts(n5) : *int
+# constraints:	4
+# nodes:	12
+# ptsets:	6
+
+
+==== Solving constraints
+
+	copy n13 <- n6
+`))
+
+	require.Equal(t, 12, m.Nodes)
+	require.Equal(t, 10, m.Constraints)
+	require.Equal(t, 12, m.OptimizedNodes)
+	require.Equal(t, 4, m.OptimizedConstraints)
+	require.Equal(t, 6, m.PointsToSets)
+	require.Equal(t, 1, m.OnlineConstraints)
+	require.Equal(t, 1, m.Canonical)
+	require.Equal(t, 2, m.Collapsed)
+	require.Equal(t, map[string]int{
+		"addr": 1, "copy": 1, "load": 1, "store": 1,
+		"offsetAddr": 1, "typeFilter": 1, "untag": 1, "invoke": 1, "intrinsic": 2,
+	}, m.Kinds)
+	require.Equal(t, map[string]int{
+		syntheticPackage:  1,
+		"example.com/pkg": 9,
+	}, m.Packages)
+}
+
+func TestAnalyzeConstraints(t *testing.T) {
+	prog, _ := buildLibrary(t)
+
+	opts := ProgramOptions{
+		Scope:       ScopePackages,
+		Packages:    []string{"lib"},
+		Synthetic:   true,
+		Constraints: true,
+	}
+	m := AnalyzeProgram(prog, opts)
+	require.True(t, m.Ok())
+	require.NotNil(t, m.Constraints)
+
+	c := m.Constraints
+	require.NotZero(t, c.Nodes)
+	require.NotZero(t, c.Constraints)
+	total := 0
+	for _, n := range c.Kinds {
+		total += n
+	}
+	require.Equal(t, c.Constraints, total)
+	total = 0
+	for _, n := range c.Packages {
+		total += n
+	}
+	require.Equal(t, c.Constraints, total)
+	require.Contains(t, c.Packages, "lib")
+	require.Contains(t, c.Packages, syntheticPath)
+	require.Contains(t, m.String(), "Constraint system metrics:")
+
+	opts.Constraints = false
+	require.Nil(t, AnalyzeProgram(prog, opts).Constraints)
+}
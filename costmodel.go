@@ -0,0 +1,301 @@
+package stamets
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// costRidge is the ridge penalty of the cost model regression, relative to the number of
+// samples. Features are standardized, such that the penalty only stabilizes the fit for
+// few or collinear samples.
+const costRidge = 1e-3
+
+// CostFeatures are the features of a points-to analysis from which its cost is predicted.
+type CostFeatures struct {
+	// Number of functions and instructions in the SSA program
+	Functions    int
+	Instructions int
+	// Number of queries and indirect queries
+	Queries         int
+	IndirectQueries int
+}
+
+// vector returns the features as regression inputs, starting with the intercept.
+func (f CostFeatures) vector() []float64 {
+	return []float64{
+		1,
+		float64(f.Functions),
+		float64(f.Instructions),
+		float64(f.Queries),
+		float64(f.IndirectQueries),
+	}
+}
+
+// ConfigCostFeatures extracts the cost features of a points-to analysis configuration.
+func ConfigCostFeatures(config *pointer.Config) CostFeatures {
+	var functions, instructions int
+	if len(config.Mains) > 0 {
+		functions, instructions = programSize(config.Mains[0].Prog)
+	}
+	return configCostFeatures(functions, instructions, config)
+}
+
+// configCostFeatures extracts the cost features of a points-to analysis configuration,
+// given the size of the program.
+func configCostFeatures(functions, instructions int, config *pointer.Config) CostFeatures {
+	return CostFeatures{
+		Functions:       functions,
+		Instructions:    instructions,
+		Queries:         len(config.Queries),
+		IndirectQueries: len(config.IndirectQueries),
+	}
+}
+
+// CostModel is a linear model of the cost of the points-to analysis, fit on past results.
+// Coefficients are ordered as the intercept, functions, instructions, queries and indirect queries.
+type CostModel struct {
+	// Number of results the duration and memory models were fit on
+	DurationSamples int
+	MemorySamples   int
+	// Coefficients predicting the duration in seconds
+	Duration []float64
+	// Coefficients predicting the retained result size in bytes
+	Memory []float64
+}
+
+func (c CostModel) String() string {
+	coefficients := func(cs []float64) string {
+		if cs == nil {
+			return "none"
+		}
+		return fmt.Sprintf("%g + %g * functions + %g * instructions + %g * queries + %g * indirect queries",
+			cs[0], cs[1], cs[2], cs[3], cs[4])
+	}
+
+	return fmt.Sprintf(`
+COST MODEL
+- Duration samples: %d
+- Duration (s): %s
+- Memory samples: %d
+- Retained result size (bytes): %s
+`,
+		c.DurationSamples,
+		coefficients(c.Duration),
+		c.MemorySamples,
+		coefficients(c.Memory),
+	)
+}
+
+// FitCostModel fits a cost model on PTA results e.g., as aggregated by AggregatePTAResults,
+// via ridge regression. Results without a program size, failed results, call graph fallbacks
+// of adaptive analyses, and results of the unification-based analysis are ignored. The memory
+// model is only fit on results with a retained result size, as measured via MeasureRetained,
+// and is omitted if there are none.
+func FitCostModel(results []PTAMetrics) (CostModel, error) {
+	var xs, memXs [][]float64
+	var ys, memYs []float64
+	for _, m := range results {
+		if !m.Ok() || m.Functions == 0 || m.Degradation >= DegradationVTA || m.Analysis != AnalysisInclusion {
+			continue
+		}
+
+		x := CostFeatures{
+			Functions:       m.Functions,
+			Instructions:    m.Instructions,
+			Queries:         m.Queries,
+			IndirectQueries: m.IndirectQueries,
+		}.vector()
+		xs, ys = append(xs, x), append(ys, m.Duration.Seconds())
+		if m.RetainedBytes > 0 {
+			memXs, memYs = append(memXs, x), append(memYs, float64(m.RetainedBytes))
+		}
+	}
+	if len(xs) == 0 {
+		return CostModel{}, errors.New("no PTA results with program sizes to fit the cost model")
+	}
+
+	c := CostModel{
+		DurationSamples: len(xs),
+		MemorySamples:   len(memXs),
+		Duration:        ridgeRegression(xs, ys),
+	}
+	if len(memXs) > 0 {
+		c.Memory = ridgeRegression(memXs, memYs)
+	}
+	return c, nil
+}
+
+// ridgeRegression fits the coefficients of a linear model, where the first input is the intercept.
+// Inputs are standardized before fitting, such that the intercept is not penalized and the penalty
+// is independent of the scale of the inputs. Coefficients are returned in the original scale.
+func ridgeRegression(xs [][]float64, ys []float64) []float64 {
+	n, k := float64(len(xs)), len(xs[0])
+
+	mean, scale := make([]float64, k), make([]float64, k)
+	for j := 1; j < k; j++ {
+		for _, x := range xs {
+			mean[j] += x[j] / n
+		}
+		for _, x := range xs {
+			scale[j] += (x[j] - mean[j]) * (x[j] - mean[j]) / n
+		}
+		scale[j] = math.Sqrt(scale[j])
+	}
+	standardize := func(x []float64, j int) float64 {
+		switch {
+		case j == 0:
+			return 1
+		case scale[j] == 0:
+			// Constant inputs carry no information beyond the intercept.
+			return 0
+		}
+		return (x[j] - mean[j]) / scale[j]
+	}
+
+	// Normal equations: (XᵀX + λI) β = Xᵀy, without penalizing the intercept.
+	a := make([][]float64, k)
+	for i := range a {
+		a[i] = make([]float64, k+1)
+		if i > 0 {
+			a[i][i] = costRidge * n
+		}
+	}
+	for r, x := range xs {
+		for i := 0; i < k; i++ {
+			xi := standardize(x, i)
+			for j := 0; j < k; j++ {
+				a[i][j] += xi * standardize(x, j)
+			}
+			a[i][k] += xi * ys[r]
+		}
+	}
+	beta := solve(a)
+
+	// Undo the standardization.
+	for j := 1; j < k; j++ {
+		if scale[j] == 0 {
+			continue
+		}
+		beta[j] /= scale[j]
+		beta[0] -= beta[j] * mean[j]
+	}
+	return beta
+}
+
+// solve solves a linear system given as an augmented matrix via Gaussian elimination with
+// partial pivoting. The system is assumed to be non-singular, which the ridge penalty ensures.
+func solve(a [][]float64) []float64 {
+	k := len(a)
+	for i := 0; i < k; i++ {
+		pivot := i
+		for r := i + 1; r < k; r++ {
+			if math.Abs(a[r][i]) > math.Abs(a[pivot][i]) {
+				pivot = r
+			}
+		}
+		a[i], a[pivot] = a[pivot], a[i]
+		for r := i + 1; r < k; r++ {
+			f := a[r][i] / a[i][i]
+			for j := i; j <= k; j++ {
+				a[r][j] -= f * a[i][j]
+			}
+		}
+	}
+
+	x := make([]float64, k)
+	for i := k - 1; i >= 0; i-- {
+		x[i] = a[i][k]
+		for j := i + 1; j < k; j++ {
+			x[i] -= a[i][j] * x[j]
+		}
+		x[i] /= a[i][i]
+	}
+	return x
+}
+
+// predict evaluates a linear model, clamping negative predictions to 0.
+func predict(cs []float64, f CostFeatures) float64 {
+	y := 0.0
+	for j, x := range f.vector() {
+		if j < len(cs) {
+			y += cs[j] * x
+		}
+	}
+	return math.Max(y, 0)
+}
+
+// PredictDuration predicts the duration of the points-to analysis with the given features.
+func (c CostModel) PredictDuration(f CostFeatures) time.Duration {
+	return time.Duration(predict(c.Duration, f) * float64(time.Second))
+}
+
+// PredictMemory predicts the retained result size in bytes of the points-to analysis with the
+// given features. It is 0 if the model has no memory samples.
+func (c CostModel) PredictMemory(f CostFeatures) uint64 {
+	return uint64(predict(c.Memory, f))
+}
+
+// CostPrediction is the predicted cost of analyzing a program at a degradation level.
+type CostPrediction struct {
+	Level DegradationLevel
+	CostFeatures
+	Duration      time.Duration
+	RetainedBytes uint64
+}
+
+// PredictProgram predicts the cost of the points-to analysis of an SSA program at every degradation
+// level of an adaptive analysis, as listed by AnalyzeAdaptive, excluding call graph fallbacks.
+// The features do not capture reflection, so dropping it is not predicted to reduce the cost.
+func (c CostModel) PredictProgram(prog *ssa.Program, opts ProgramOptions) ([]CostPrediction, error) {
+	mains, _, err := programEntries(prog, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	functions, instructions := programSize(prog)
+	var ps []CostPrediction
+	for _, d := range degradations(opts) {
+		f := configCostFeatures(functions, instructions, pointerConfig(prog, mains, d.opts))
+		ps = append(ps, CostPrediction{
+			Level:         d.level,
+			CostFeatures:  f,
+			Duration:      c.PredictDuration(f),
+			RetainedBytes: c.PredictMemory(f),
+		})
+	}
+	return ps, nil
+}
+
+// Degrade finds the least degraded configuration of the points-to analysis of an SSA program
+// predicted to complete in the time limit. It reports false if every configuration is predicted
+// to exceed the limit, in which case the analysis of the program may be skipped.
+func (c CostModel) Degrade(prog *ssa.Program, opts ProgramOptions, t time.Duration) (CostPrediction, bool, error) {
+	ps, err := c.PredictProgram(prog, opts)
+	if err != nil {
+		return CostPrediction{}, false, err
+	}
+	for _, p := range ps {
+		if p.Duration <= t {
+			return p, true, nil
+		}
+	}
+	return ps[len(ps)-1], false, nil
+}
+
+// AnalyzePredicted runs the points-to analysis on an SSA program adaptively like AnalyzeAdaptive,
+// but skips the configurations predicted by the cost model to exceed their time limit. If every
+// configuration is predicted to exceed its limit, only the call graph is constructed.
+func AnalyzePredicted(prog *ssa.Program, opts ProgramOptions, budget time.Duration, model CostModel) PTAMetrics {
+	var functions, instructions int
+	if prog != nil {
+		functions, instructions = programSize(prog)
+	}
+	return analyzeAdaptive(prog, opts, budget, func(config *pointer.Config, t time.Duration) bool {
+		return model.PredictDuration(configCostFeatures(functions, instructions, config)) <= t
+	})
+}
@@ -0,0 +1,103 @@
+package stamets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/pointer"
+)
+
+func TestFitCostModel(t *testing.T) {
+	_, err := FitCostModel(nil)
+	require.Error(t, err)
+
+	result := func(functions, instructions, queries int, seconds float64, level DegradationLevel) PTAMetrics {
+		return PTAMetrics{
+			BaseMetrics: BaseMetrics[*pointer.Result]{
+				Duration: time.Duration(seconds * float64(time.Second)),
+			},
+			Functions:       functions,
+			Instructions:    instructions,
+			Queries:         queries,
+			IndirectQueries: queries / 10,
+			RetainedBytes:   uint64(100 * queries),
+			Degradation:     level,
+		}
+	}
+
+	// Durations are exactly 1s + 1ms per instruction + 10ms per query.
+	var results []PTAMetrics
+	for i := 1; i <= 20; i++ {
+		instructions, queries := 1000*i, 100*(i%7+1)
+		results = append(results, result(10*i, instructions, queries,
+			1+0.001*float64(instructions)+0.01*float64(queries), DegradationNone))
+	}
+	// Results without program sizes, call graph fallbacks and unification results are ignored.
+	unification := result(100, 1000, 100, 1000, DegradationNone)
+	unification.Analysis = AnalysisUnification
+	results = append(results,
+		result(0, 0, 100, 1000, DegradationNone),
+		result(100, 1000, 0, 1000, DegradationCHA),
+		unification)
+
+	c, err := FitCostModel(results)
+	require.NoError(t, err)
+	require.Equal(t, 20, c.DurationSamples)
+	require.Equal(t, 20, c.MemorySamples)
+
+	f := CostFeatures{Functions: 150, Instructions: 15000, Queries: 500, IndirectQueries: 50}
+	require.InEpsilon(t, 21*time.Second, c.PredictDuration(f), 0.05)
+	require.InEpsilon(t, 50000, c.PredictMemory(f), 0.05)
+	// Predictions are never negative.
+	require.Zero(t, c.PredictDuration(CostFeatures{Instructions: -1 << 30}))
+	require.Contains(t, c.String(), "- Duration samples: 20")
+}
+
+func TestCostModelDegrade(t *testing.T) {
+	prog, _ := buildLibrary(t)
+	opts := ProgramOptions{
+		Scope:      ScopePackages,
+		Packages:   []string{"lib"},
+		Synthetic:  true,
+		Reflection: true,
+	}
+
+	// Every indirect query costs an hour.
+	c := CostModel{Duration: []float64{0, 0, 0, 0, 3600}}
+	ps, err := c.PredictProgram(prog, opts)
+	require.NoError(t, err)
+	require.Len(t, ps, 3)
+	require.NotZero(t, ps[0].Functions)
+	require.NotZero(t, ps[0].IndirectQueries)
+	require.Equal(t, time.Duration(ps[0].IndirectQueries)*time.Hour, ps[0].Duration)
+	require.Zero(t, ps[2].Duration)
+
+	p, ok, err := c.Degrade(prog, opts, time.Millisecond)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, DegradationNoIndirectQueries, p.Level)
+
+	m := AnalyzePredicted(prog, opts, time.Minute, c)
+	require.True(t, m.Ok())
+	require.Equal(t, DegradationNoIndirectQueries, m.Degradation)
+	require.NotZero(t, m.Queries)
+	require.Zero(t, m.IndirectQueries)
+
+	// Every configuration takes an hour.
+	c = CostModel{Duration: []float64{3600, 0, 0, 0, 0}}
+	_, ok, err = c.Degrade(prog, opts, time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	m = AnalyzePredicted(prog, opts, time.Minute, c)
+	require.True(t, m.Ok())
+	require.GreaterOrEqual(t, m.Degradation, DegradationVTA)
+	require.Zero(t, m.Queries)
+
+	_, _, err = c.Degrade(prog, ProgramOptions{}, time.Minute)
+	require.Error(t, err)
+
+	_, err = AnalyzePredicted(nil, opts, time.Minute, c).Unpack()
+	require.ErrorIs(t, err, errNoProgram)
+}
@@ -0,0 +1,252 @@
+package stamets
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/packages"
+)
+
+// heaviestDependencies is the number of heaviest dependencies reported
+// in import graph metrics.
+const heaviestDependencies = 10
+
+// PackageWeight is the number of files of a package and all of its
+// transitive dependencies.
+type PackageWeight struct {
+	ID    string
+	Files int
+}
+
+// ImportGraphMetrics encodes metrics about the import graph of loaded packages
+// e.g., as produced by PackagesLoad.
+type ImportGraphMetrics struct {
+	BaseMetrics[[]*packages.Package]
+
+	// Number of packages in the import graph, including dependencies
+	Packages int
+
+	// Number of importers of every package
+	FanIn Series[int]
+	// Number of imports of every package
+	FanOut Series[int]
+
+	// Length of the longest import chain
+	Depth int
+
+	// Import cycles formed by identifying test packages with the packages
+	// under test. Every cycle is given as a sorted list of package paths.
+	TestCycles [][]string
+
+	// Packages with the most files in their transitive dependencies,
+	// in descending order.
+	HeaviestDependencies []PackageWeight
+}
+
+func (m ImportGraphMetrics) String() string {
+	str := fmt.Sprintf(`
+IMPORT GRAPH METRICS
+- Number of packages: %d
+- Depth: %d
+Package fan-in metrics:
+	- P50: %d
+	- P90: %d
+	- P99: %d
+	- Max: %d
+	- Most common fan-in: %d
+Package fan-out metrics:
+	- P50: %d
+	- P90: %d
+	- P99: %d
+	- Max: %d
+	- Most common fan-out: %d
+- Number of import cycles through test packages: %d
+`,
+		m.Packages,
+		m.Depth,
+		m.FanIn.P50(),
+		m.FanIn.P90(),
+		m.FanIn.P99(),
+		m.FanIn.Max(),
+		m.FanIn.Mode(),
+		m.FanOut.P50(),
+		m.FanOut.P90(),
+		m.FanOut.P99(),
+		m.FanOut.Max(),
+		m.FanOut.Mode(),
+		len(m.TestCycles),
+	)
+
+	for _, cycle := range m.TestCycles {
+		str += fmt.Sprintf("\t- %s\n", strings.Join(cycle, ", "))
+	}
+
+	str += "Heaviest dependencies by transitive file count:\n"
+	for _, w := range m.HeaviestDependencies {
+		str += fmt.Sprintf("\t- %s: %d\n", w.ID, w.Files)
+	}
+
+	return str
+}
+
+// GetImportGraphMetrics constructs metrics from the import graph of the given packages.
+func GetImportGraphMetrics(pkgs []*packages.Package) ImportGraphMetrics {
+	m := ImportGraphMetrics{
+		BaseMetrics: BaseMetrics[[]*packages.Package]{
+			Payload: pkgs,
+		},
+	}
+
+	var all []*packages.Package
+	importers := make(map[*packages.Package]int)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		all = append(all, pkg)
+		for _, imp := range pkg.Imports {
+			importers[imp]++
+		}
+	})
+
+	m.Packages = len(all)
+	m.FanIn = MakeSeries(func(pkg *packages.Package) int {
+		return importers[pkg]
+	}, all...)
+	m.FanOut = MakeSeries(func(pkg *packages.Package) int {
+		return len(pkg.Imports)
+	}, all...)
+
+	// The import graph is acyclic, therefore the depth of a package may be computed
+	// from the depth of its imports, which are visited first.
+	depths := make(map[*packages.Package]int)
+	for _, pkg := range all {
+		for _, imp := range pkg.Imports {
+			if depths[pkg] < depths[imp]+1 {
+				depths[pkg] = depths[imp] + 1
+			}
+		}
+		if m.Depth < depths[pkg] {
+			m.Depth = depths[pkg]
+		}
+	}
+
+	m.TestCycles = testImportCycles(all)
+	m.HeaviestDependencies = heaviest(all, heaviestDependencies)
+
+	return m
+}
+
+// heaviest computes the n packages with the most files in their transitive dependencies.
+func heaviest(all []*packages.Package, n int) []PackageWeight {
+	weights := make([]PackageWeight, 0, len(all))
+	for _, pkg := range all {
+		w := PackageWeight{ID: pkg.ID}
+		packages.Visit([]*packages.Package{pkg}, nil, func(dep *packages.Package) {
+			w.Files += len(dep.CompiledGoFiles)
+		})
+		weights = append(weights, w)
+	}
+
+	slices.SortStableFunc(weights, func(a, b PackageWeight) bool {
+		if a.Files == b.Files {
+			return a.ID < b.ID
+		}
+		return a.Files > b.Files
+	})
+	if len(weights) > n {
+		weights = weights[:n]
+	}
+	return weights
+}
+
+// importGraphPath identifies test packages with the package under test,
+// by stripping the test suffixes from the package path.
+func importGraphPath(pkg *packages.Package) string {
+	switch {
+	case pkg.Name == "main" && strings.HasSuffix(pkg.PkgPath, ".test"):
+		// Generated test main package
+		return strings.TrimSuffix(pkg.PkgPath, ".test")
+	case strings.HasSuffix(pkg.Name, "_test"):
+		// External test package
+		return strings.TrimSuffix(pkg.PkgPath, "_test")
+	}
+	return pkg.PkgPath
+}
+
+// testImportCycles finds the import cycles that emerge when identifying test packages
+// with the packages under test. Since the import graph itself is acyclic, such
+// cycles always go through test packages.
+func testImportCycles(all []*packages.Package) [][]string {
+	edges := make(map[string]map[string]struct{})
+	for _, pkg := range all {
+		from := importGraphPath(pkg)
+		if edges[from] == nil {
+			edges[from] = make(map[string]struct{})
+		}
+		for _, imp := range pkg.Imports {
+			if to := importGraphPath(imp); to != from {
+				edges[from][to] = struct{}{}
+			}
+		}
+	}
+
+	nodes := maps.Keys(edges)
+	slices.Sort(nodes)
+
+	// Tarjan's strongly connected components algorithm.
+	var (
+		cycles  [][]string
+		stack   []string
+		index   = make(map[string]int)
+		lowlink = make(map[string]int)
+		onStack = make(map[string]bool)
+	)
+	var strongConnect func(string)
+	strongConnect = func(v string) {
+		index[v] = len(index)
+		lowlink[v] = index[v]
+		stack = append(stack, v)
+		onStack[v] = true
+
+		succs := maps.Keys(edges[v])
+		slices.Sort(succs)
+		for _, w := range succs {
+			if _, ok := index[w]; !ok {
+				strongConnect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
+				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
+			}
+		}
+
+		if lowlink[v] == index[v] {
+			var scc []string
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				scc = append(scc, w)
+				if w == v {
+					break
+				}
+			}
+			if len(scc) > 1 {
+				slices.Sort(scc)
+				cycles = append(cycles, scc)
+			}
+		}
+	}
+
+	for _, v := range nodes {
+		if _, ok := index[v]; !ok {
+			strongConnect(v)
+		}
+	}
+
+	slices.SortFunc(cycles, func(a, b []string) bool {
+		return a[0] < b[0]
+	})
+	return cycles
+}
@@ -0,0 +1,79 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+// Construct an import graph to use for testing, including the test
+// variants produced when loading packages with tests.
+//
+// Import graph:
+//
+//	[p.test]---->[p_test [p.test]]---->[q [p.test]]
+//	    |               |                   |
+//	    |               V                   |
+//	    '-------->[p [p.test]]<-------------'
+//	                    |
+//	                    V
+//	                   [r]
+//
+// Files: p.test = 1, p_test = 1, q = 2, p = 3, r = 4
+func makeImportGraph() (root *packages.Package, all []*packages.Package) {
+	makePkg := func(id, name, path string, files int, imports ...*packages.Package) *packages.Package {
+		pkg := &packages.Package{
+			ID:              id,
+			Name:            name,
+			PkgPath:         path,
+			CompiledGoFiles: make([]string, files),
+			Imports:         make(map[string]*packages.Package),
+		}
+		for _, imp := range imports {
+			pkg.Imports[imp.PkgPath] = imp
+		}
+		all = append(all, pkg)
+		return pkg
+	}
+
+	r := makePkg("r", "r", "r", 4)
+	p := makePkg("p [p.test]", "p", "p", 3, r)
+	q := makePkg("q [p.test]", "q", "q", 2, p)
+	xtest := makePkg("p_test [p.test]", "p_test", "p_test", 1, p, q)
+	root = makePkg("p.test", "main", "p.test", 1, p, xtest)
+	return
+}
+
+func TestImportGraphMetrics(t *testing.T) {
+	root, _ := makeImportGraph()
+
+	m := GetImportGraphMetrics([]*packages.Package{root})
+
+	require.Equal(t, 5, m.Packages)
+	require.Equal(t, Series[int]{0, 1, 1, 1, 3}, m.FanIn)
+	require.Equal(t, Series[int]{0, 1, 1, 2, 2}, m.FanOut)
+	require.Equal(t, 4, m.Depth)
+	require.Equal(t, [][]string{{"p", "q"}}, m.TestCycles)
+	require.Equal(t, []PackageWeight{
+		{ID: "p.test", Files: 11},
+		{ID: "p_test [p.test]", Files: 10},
+		{ID: "q [p.test]", Files: 9},
+		{ID: "p [p.test]", Files: 7},
+		{ID: "r", Files: 4},
+	}, m.HeaviestDependencies)
+
+	m = GetImportGraphMetrics(nil)
+	require.Zero(t, m.Packages)
+	require.Zero(t, m.Depth)
+	require.Empty(t, m.TestCycles)
+}
+
+func TestHeaviest(t *testing.T) {
+	_, all := makeImportGraph()
+
+	require.Equal(t, []PackageWeight{
+		{ID: "p.test", Files: 11},
+		{ID: "p_test [p.test]", Files: 10},
+	}, heaviest(all, 2))
+}
@@ -0,0 +1,184 @@
+package stamets
+
+import (
+	"fmt"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// LabelSite is a points-to label, with the source position of its allocation site.
+type LabelSite struct {
+	Label    string
+	Position token.Position
+}
+
+// ImpreciseQuery describes a PTA query by the size of its points-to set.
+type ImpreciseQuery struct {
+	Value ssa.Value
+	// Function of the queried value, nil for globals
+	Function *ssa.Function
+	// Source position of the queried value, or of its function
+	// if the value has no position
+	Position token.Position
+	Type     types.Type
+	// Whether the query is indirect i.e., about the values pointed to by the queried value
+	Indirect bool
+
+	// Points-to set size
+	Size int
+	// Sample of points-to labels, ordered by allocation site
+	Labels []LabelSite
+}
+
+func (q ImpreciseQuery) String() string {
+	query := "Query"
+	if q.Indirect {
+		query = "Indirect query"
+	}
+	fn := "(global)"
+	if q.Function != nil {
+		fn = q.Function.String()
+	}
+
+	str := fmt.Sprintf(`%s %s at %s:
+	- Function: %s
+	- Type: %s
+	- Points-to set size: %d
+`,
+		query,
+		q.Value.Name(),
+		q.Position,
+		fn,
+		q.Type,
+		q.Size,
+	)
+	for _, l := range q.Labels {
+		str += fmt.Sprintf("\t\t- %s allocated at %s\n", l.Label, l.Position)
+	}
+	return str
+}
+
+// ImpreciseQueries lists the PTA queries with the largest points-to sets, in descending order.
+type ImpreciseQueries []ImpreciseQuery
+
+func (qs ImpreciseQueries) String() string {
+	str := fmt.Sprintf(`
+IMPRECISE QUERIES
+- Number of queries: %d
+`, len(qs))
+	for _, q := range qs {
+		str += q.String()
+	}
+	return str
+}
+
+// ImpreciseQueries computes the n queries, direct or indirect, with the largest points-to sets.
+// Source positions are resolved in the file set of the given program, which must be the analyzed
+// program. Every query includes a sample of at most `samples` points-to labels. It fails if the PTA
+// result does not include the points-to sets of its queries, or if n or samples is negative.
+func (m PTAMetrics) ImpreciseQueries(prog *ssa.Program, n, samples int) (ImpreciseQueries, error) {
+	if n < 0 || samples < 0 {
+		return nil, fmt.Errorf("invalid number of queries (%d) or sampled labels (%d)", n, samples)
+	}
+	res, err := m.queryResult()
+	if err != nil {
+		return nil, err
+	}
+
+	var qs ImpreciseQueries
+	add := func(queries map[ssa.Value]pointer.Pointer, indirect bool) {
+		for v, pt := range queries {
+			fn := v.Parent()
+			pos := v.Pos()
+			if !pos.IsValid() && fn != nil {
+				pos = fn.Pos()
+			}
+			t := v.Type()
+			if indirect {
+				t = t.Underlying().(*types.Pointer).Elem()
+			}
+
+			qs = append(qs, ImpreciseQuery{
+				Value:    v,
+				Function: fn,
+				Position: prog.Fset.Position(pos),
+				Type:     t,
+				Indirect: indirect,
+				Size:     len(pt.PointsTo().Labels()),
+			})
+		}
+	}
+	add(res.Queries, false)
+	add(res.IndirectQueries, true)
+
+	slices.SortFunc(qs, func(a, b ImpreciseQuery) bool {
+		switch {
+		case a.Size != b.Size:
+			return a.Size > b.Size
+		case a.Position != b.Position:
+			return positionLess(a.Position, b.Position)
+		case a.Value.Name() != b.Value.Name():
+			return a.Value.Name() < b.Value.Name()
+		}
+		return !a.Indirect && b.Indirect
+	})
+	if len(qs) > n {
+		qs = qs[:n]
+	}
+
+	// Labels are only sampled for the reported queries.
+	for i, q := range qs {
+		pt := res.Queries[q.Value]
+		if q.Indirect {
+			pt = res.IndirectQueries[q.Value]
+		}
+		qs[i].Labels = labelSites(prog, pt.PointsTo().Labels(), samples)
+	}
+
+	return qs, nil
+}
+
+// labelSites samples at most n labels, ordered by the source positions of their allocation sites.
+// Allocation sites without a position are approximated by the position of their function.
+func labelSites(prog *ssa.Program, labels []*pointer.Label, n int) []LabelSite {
+	sites := make([]LabelSite, 0, len(labels))
+	for _, l := range labels {
+		pos := l.Pos()
+		if v := l.Value(); !pos.IsValid() && v != nil && v.Parent() != nil {
+			pos = v.Parent().Pos()
+		}
+		sites = append(sites, LabelSite{
+			Label:    l.String(),
+			Position: prog.Fset.Position(pos),
+		})
+	}
+
+	slices.SortFunc(sites, func(a, b LabelSite) bool {
+		if a.Position != b.Position {
+			return positionLess(a.Position, b.Position)
+		}
+		return a.Label < b.Label
+	})
+	if len(sites) > n {
+		sites = sites[:n]
+	}
+	return sites
+}
+
+// positionLess orders source positions by file name, line and column.
+// Invalid positions are ordered last.
+func positionLess(a, b token.Position) bool {
+	switch {
+	case a.IsValid() != b.IsValid():
+		return a.IsValid()
+	case a.Filename != b.Filename:
+		return a.Filename < b.Filename
+	case a.Line != b.Line:
+		return a.Line < b.Line
+	}
+	return a.Column < b.Column
+}
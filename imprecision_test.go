@@ -0,0 +1,50 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpreciseQueries(t *testing.T) {
+	prog, _ := buildLibrary(t)
+
+	m := AnalyzeProgram(prog, ProgramOptions{
+		Scope:     ScopePackages,
+		Packages:  []string{"lib"},
+		Synthetic: true,
+	})
+	require.True(t, m.Ok())
+	_, err := PTAMetrics{}.ImpreciseQueries(prog, 3, 1)
+	require.Error(t, err)
+
+	qs, err := m.ImpreciseQueries(prog, 3, 1)
+	require.NoError(t, err)
+	require.Len(t, qs, 3)
+	require.Equal(t, m.PointsToSetSizeMax, qs[0].Size)
+	for i, q := range qs {
+		if i > 0 {
+			require.LessOrEqual(t, q.Size, qs[i-1].Size)
+		}
+		require.Equal(t, "lib.go", q.Position.Filename)
+		require.NotNil(t, q.Function)
+		require.LessOrEqual(t, len(q.Labels), 1)
+		for _, l := range q.Labels {
+			require.True(t, l.Position.IsValid())
+		}
+	}
+	require.Contains(t, qs.String(), "IMPRECISE QUERIES")
+
+	all, err := m.ImpreciseQueries(prog, m.Queries+m.IndirectQueries+1, 0)
+	require.NoError(t, err)
+	require.Len(t, all, m.Queries+m.IndirectQueries)
+	for _, q := range all {
+		require.Empty(t, q.Labels)
+	}
+
+	// Negative limits are rejected, instead of truncating out of bounds.
+	_, err = m.ImpreciseQueries(prog, -1, 1)
+	require.Error(t, err)
+	_, err = m.ImpreciseQueries(prog, 3, -1)
+	require.Error(t, err)
+}
@@ -0,0 +1,150 @@
+package stamets
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// LabelKind classifies points-to labels by the kind of their allocation site.
+type LabelKind string
+
+const (
+	// LabelAlloc labels heap or stack allocations.
+	LabelAlloc LabelKind = "Alloc"
+	// LabelMakeChan labels channel allocations.
+	LabelMakeChan LabelKind = "MakeChan"
+	// LabelMakeMap labels map allocations.
+	LabelMakeMap LabelKind = "MakeMap"
+	// LabelMakeSlice labels slice allocations, including by append and
+	// conversions from strings.
+	LabelMakeSlice LabelKind = "MakeSlice"
+	// LabelMakeClosure labels closure allocations.
+	LabelMakeClosure LabelKind = "MakeClosure"
+	// LabelMakeInterface labels the boxes of interface conversions.
+	LabelMakeInterface LabelKind = "MakeInterface"
+	// LabelGlobal labels global variables.
+	LabelGlobal LabelKind = "Global"
+	// LabelFunction labels function objects.
+	LabelFunction LabelKind = "Function"
+	// LabelReflection labels objects synthesized by the reflection model,
+	// e.g., runtime types or allocations by reflect.New.
+	LabelReflection LabelKind = "Reflection"
+	// LabelOther labels any other objects e.g., allocated by intrinsics.
+	LabelOther LabelKind = "Other"
+)
+
+// labelKinds lists all label kinds in the order they are reported.
+var labelKinds = []LabelKind{
+	LabelAlloc,
+	LabelMakeChan,
+	LabelMakeMap,
+	LabelMakeSlice,
+	LabelMakeClosure,
+	LabelMakeInterface,
+	LabelGlobal,
+	LabelFunction,
+	LabelReflection,
+	LabelOther,
+}
+
+// labelKind classifies a points-to label by its allocation site.
+func labelKind(l *pointer.Label) LabelKind {
+	if l.ReflectType() != nil {
+		return LabelReflection
+	}
+
+	switch v := l.Value().(type) {
+	case *ssa.Alloc:
+		return LabelAlloc
+	case *ssa.MakeChan:
+		return LabelMakeChan
+	case *ssa.MakeMap:
+		return LabelMakeMap
+	case *ssa.MakeSlice, *ssa.Convert:
+		return LabelMakeSlice
+	case *ssa.MakeClosure:
+		return LabelMakeClosure
+	case *ssa.MakeInterface:
+		return LabelMakeInterface
+	case *ssa.Global:
+		return LabelGlobal
+	case *ssa.Function:
+		return LabelFunction
+	case *ssa.Call:
+		if b, ok := v.Call.Value.(*ssa.Builtin); ok && b.Name() == "append" {
+			return LabelMakeSlice
+		}
+		if fn := v.Call.StaticCallee(); fn != nil && packagePath(functionPackage(fn)) == "reflect" {
+			return LabelReflection
+		}
+	case nil:
+		// Objects allocated by intrinsics have no value, and are only
+		// identified by the function allocating them.
+		if strings.HasPrefix(l.String(), "<alloc in reflect.") {
+			return LabelReflection
+		}
+	}
+	return LabelOther
+}
+
+// LabelKindSizes describes the points-to labels of a single kind across PTA queries.
+type LabelKindSizes struct {
+	// Number of queries pointing to labels of the kind
+	Queries int
+	// Number of labels of the kind, summed over all queries
+	Labels int
+	// Distribution of the number of labels of the kind, over the queries pointing to them
+	Distribution
+}
+
+// LabelKindMetrics computes the distributions of the numbers of points-to labels per
+// allocation kind, over the queries pointing to labels of each kind. These show which
+// kinds of objects e.g., channels, closures or reflection, inflate points-to sets.
+func (m PTAMetrics) LabelKindMetrics() PTAMetrics {
+	res, err := m.queryResult()
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	sizes := make(map[LabelKind][]int)
+	for _, pt := range res.Queries {
+		counts := make(map[LabelKind]int)
+		for _, l := range pt.PointsTo().Labels() {
+			counts[labelKind(l)]++
+		}
+		for kind, n := range counts {
+			sizes[kind] = append(sizes[kind], n)
+		}
+	}
+
+	m.LabelKinds = make(map[LabelKind]LabelKindSizes, len(sizes))
+	for kind, ns := range sizes {
+		s := LabelKindSizes{Queries: len(ns)}
+		for _, n := range ns {
+			s.Labels += n
+		}
+		s.Distribution = makeDistribution(ns)
+		m.LabelKinds[kind] = s
+	}
+
+	return m
+}
+
+// labelKindsString renders the label kind metrics, in the order of labelKinds.
+func (m PTAMetrics) labelKindsString() string {
+	if m.LabelKinds == nil {
+		return ""
+	}
+
+	str := "Points-to labels by allocation kind:\n"
+	for _, kind := range labelKinds {
+		if s, ok := m.LabelKinds[kind]; ok {
+			str += fmt.Sprintf("\t- %s: %d labels in %d queries, %s\n", kind, s.Labels, s.Queries, s.Distribution)
+		}
+	}
+	return str
+}
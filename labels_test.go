@@ -0,0 +1,48 @@
+package stamets
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLabelKindMetrics(t *testing.T) {
+	prog, _ := buildLibrary(t)
+
+	m := AnalyzeProgram(prog, ProgramOptions{
+		Scope:     ScopePackages,
+		Packages:  []string{"lib"},
+		Synthetic: true,
+	})
+	require.True(t, m.Ok())
+	require.Nil(t, m.LabelKinds)
+	require.NotContains(t, m.String(), "allocation kind")
+
+	m = m.LabelKindMetrics()
+	// Fresh arguments of synthetic calls are allocated by new and make. Slices of
+	// constant length are allocated as arrays.
+	for _, kind := range []LabelKind{LabelAlloc, LabelMakeChan, LabelMakeMap} {
+		require.Contains(t, m.LabelKinds, kind)
+		s := m.LabelKinds[kind]
+		require.NotZero(t, s.Queries)
+		require.GreaterOrEqual(t, s.Labels, s.Queries)
+		require.NotZero(t, s.P50)
+		require.LessOrEqual(t, s.P50, s.Max)
+	}
+	require.NotContains(t, m.LabelKinds, LabelReflection)
+
+	total := 0
+	for _, s := range m.LabelKinds {
+		total += s.Labels
+	}
+	expected := 0
+	for _, pt := range m.Payload.Queries {
+		expected += len(pt.PointsTo().Labels())
+	}
+	require.Equal(t, expected, total)
+
+	require.Contains(t, m.String(), "\t- Alloc: ")
+	// Label kind rows do not interfere with unparsing.
+	require.Len(t, UnparsePTAResultsFromReader(strings.NewReader(m.String()+m.String())), 2)
+}
@@ -0,0 +1,119 @@
+package stamets
+
+import (
+	"fmt"
+	"runtime"
+	"unsafe"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// allocatedBy measures the number of heap bytes allocated while running f.
+// Allocations by concurrently running goroutines are also included.
+func allocatedBy(f func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	f()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// liveHeap measures the number of bytes of reachable heap objects, after
+// a garbage collection.
+func liveHeap() uint64 {
+	var stats runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+// MeasureRetained runs a points-to analysis with the given configuration e.g., via Analyze, and
+// measures the size of its result as the growth of the live heap over the analysis. Garbage
+// collections are forced before and after the analysis, which are excluded from its duration.
+// Allocations by concurrently running goroutines are also included.
+func MeasureRetained(analyze func(*pointer.Config) PTAMetrics, config *pointer.Config) PTAMetrics {
+	heap := liveHeap()
+	m := analyze(config)
+	if retained := liveHeap(); m.Ok() && retained > heap {
+		m.RetainedBytes = retained - heap
+	}
+	return m
+}
+
+// Approximate sizes of the structures of PTA results. Map entries are estimated
+// by the sizes of their keys and values.
+const (
+	querySize   = unsafe.Sizeof(ssa.Value(nil)) + unsafe.Sizeof(pointer.Pointer{})
+	nodeSize    = unsafe.Sizeof(callgraph.Node{}) + unsafe.Sizeof((*ssa.Function)(nil)) + unsafe.Sizeof((*callgraph.Node)(nil))
+	edgeSize    = unsafe.Sizeof(callgraph.Edge{}) + 2*unsafe.Sizeof((*callgraph.Edge)(nil))
+	elementSize = unsafe.Sizeof(uintptr(0))
+	warningSize = unsafe.Sizeof(pointer.Warning{})
+)
+
+// ResultSize estimates the size of a PTA result by counting its structures.
+type ResultSize struct {
+	// Number of points-to set elements, over all direct and indirect queries
+	Labels int
+	// Number of call graph nodes and edges
+	CallGraphNodes int
+	CallGraphEdges int
+	// Estimated number of bytes. Points-to set elements are estimated at one word each.
+	// The estimate excludes the internal state of the analysis, which remains reachable
+	// from the queries, and is therefore a lower bound of the retained size.
+	Bytes uint64
+}
+
+func (s ResultSize) String() string {
+	return fmt.Sprintf(`Result size estimate:
+	- Number of points-to set elements: %d
+	- Number of call graph nodes: %d
+	- Number of call graph edges: %d
+	- Estimated bytes: %d
+`,
+		s.Labels,
+		s.CallGraphNodes,
+		s.CallGraphEdges,
+		s.Bytes,
+	)
+}
+
+// ResultSizeMetrics estimates the size of the PTA result by counting its labels,
+// points-to sets, and call graph nodes and edges.
+func (m PTAMetrics) ResultSizeMetrics() PTAMetrics {
+	res, err := m.queryResult()
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	s := &ResultSize{}
+	for _, queries := range []map[ssa.Value]pointer.Pointer{res.Queries, res.IndirectQueries} {
+		for _, pt := range queries {
+			s.Labels += len(pt.PointsTo().Labels())
+		}
+		s.Bytes += uint64(len(queries)) * uint64(querySize)
+	}
+	s.Bytes += uint64(s.Labels) * uint64(elementSize)
+
+	if cg := res.CallGraph; cg != nil {
+		cgm := CallGraphMetrics{BaseMetrics: BaseMetrics[*callgraph.Graph]{Payload: cg}}
+		s.CallGraphNodes = cgm.NumberOfFunctions()
+		s.CallGraphEdges = cgm.NumberOfEdges()
+		s.Bytes += uint64(s.CallGraphNodes)*uint64(nodeSize) + uint64(s.CallGraphEdges)*uint64(edgeSize)
+	}
+	s.Bytes += uint64(len(res.Warnings)) * uint64(warningSize)
+
+	m.ResultSize = s
+	return m
+}
+
+// heapAlloc reads the number of bytes of allocated heap objects, including
+// unreachable objects not yet collected. Unlike liveHeap, it does not force
+// a garbage collection, and is cheap enough for frequent polling.
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,60 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/pointer"
+)
+
+func TestResultSizeMetrics(t *testing.T) {
+	prog, _ := buildLibrary(t)
+
+	m := AnalyzeProgram(prog, ProgramOptions{
+		Scope:          ScopePackages,
+		Packages:       []string{"lib"},
+		Synthetic:      true,
+		BuildCallGraph: true,
+	})
+	require.True(t, m.Ok())
+	require.Zero(t, m.RetainedBytes)
+	require.Nil(t, m.ResultSize)
+
+	m = m.ResultSizeMetrics()
+	require.NotNil(t, m.ResultSize)
+
+	labels := 0
+	for _, pt := range m.Payload.Queries {
+		labels += len(pt.PointsTo().Labels())
+	}
+	for _, pt := range m.Payload.IndirectQueries {
+		labels += len(pt.PointsTo().Labels())
+	}
+	require.Equal(t, labels, m.ResultSize.Labels)
+	require.Equal(t, len(m.Payload.CallGraph.Nodes), m.ResultSize.CallGraphNodes)
+	require.NotZero(t, m.ResultSize.CallGraphEdges)
+	require.Greater(t, m.ResultSize.Bytes, uint64(labels))
+	require.Contains(t, m.String(), "Result size estimate:")
+}
+
+func TestMeasureRetained(t *testing.T) {
+	prog, _ := buildLibrary(t)
+	opts := ProgramOptions{
+		Scope:           ScopePackages,
+		Packages:        []string{"lib"},
+		Synthetic:       true,
+		MeasureRetained: true,
+	}
+
+	// The first analysis of the program releases memory, which offsets the small result.
+	require.True(t, AnalyzeProgram(prog, opts).Ok())
+
+	// The result retains the state of the analysis.
+	m := AnalyzeProgram(prog, opts)
+	require.True(t, m.Ok())
+	require.NotZero(t, m.RetainedBytes)
+
+	m = MeasureRetained(Analyze, &pointer.Config{})
+	require.False(t, m.Ok())
+	require.Zero(t, m.RetainedBytes)
+}
@@ -0,0 +1,175 @@
+package stamets
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// standardModule is the name under which standard library packages are attributed.
+const standardModule = "std"
+
+// ModuleAttribution counts the analysis artifacts attributed to a module.
+type ModuleAttribution struct {
+	Path    string
+	Version string
+
+	// Number of SSA functions in packages of the module
+	Functions int
+	// Number of call graph nodes for functions in packages of the module
+	CallGraphNodes int
+	// Number of PTA queries (including indirect queries) for values in packages of the module
+	Queries int
+}
+
+// ModuleMetrics encodes metrics about the modules of loaded packages, and attributes
+// analysis artifacts to the module responsible for them.
+type ModuleMetrics struct {
+	BaseMetrics[[]*packages.Package]
+
+	// Number of modules, excluding the standard library
+	Modules int
+	// Paths of replaced modules
+	Replaced []string
+	// Paths of vendored modules
+	Vendored []string
+	// Module versions, indexed by module path
+	Versions map[string]string
+
+	// Analysis artifacts per module, ranked by attributed cost.
+	Attribution []ModuleAttribution
+}
+
+func (m ModuleMetrics) String() string {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, `
+MODULE METRICS
+- Number of modules: %d
+- Number of replaced modules: %d
+- Number of vendored modules: %d
+Modules ranked by attributed analysis cost:
+`,
+		m.Modules,
+		len(m.Replaced),
+		len(m.Vendored),
+	)
+
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tRank\tModule\tVersion\tFunctions\tCall graph nodes\tQueries")
+	for i, a := range m.Attribution {
+		fmt.Fprintf(w, "\t%d\t%s\t%s\t%d\t%d\t%d\n", i+1, a.Path, a.Version, a.Functions, a.CallGraphNodes, a.Queries)
+	}
+	w.Flush()
+
+	return buf.String()
+}
+
+// GetModuleMetrics constructs module metrics for the given packages. SSA functions, call graph
+// nodes and PTA queries are attributed to modules from the SSA program, call graph and PTA result,
+// respectively. Any of them may be nil, in which case the corresponding attribution is skipped.
+//
+// Module information is only available if packages are loaded with packages.NeedModule, as is done
+// by PackagesLoad. Packages without a module are attributed to the standard library.
+func GetModuleMetrics(pkgs []*packages.Package, prog *ssa.Program, cg *callgraph.Graph, pta *pointer.Result) ModuleMetrics {
+	m := ModuleMetrics{
+		BaseMetrics: BaseMetrics[[]*packages.Package]{
+			Payload: pkgs,
+		},
+		Versions: make(map[string]string),
+	}
+
+	// Modules indexed by package path, and the vendor directories of main modules.
+	modules := make(map[string]*packages.Module)
+	var vendorDirs []string
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		modules[pkg.PkgPath] = pkg.Module
+		if pkg.Module != nil && pkg.Module.Main && pkg.Module.Dir != "" {
+			vendorDirs = append(vendorDirs, filepath.Join(pkg.Module.Dir, "vendor")+string(filepath.Separator))
+		}
+	})
+
+	attribution := make(map[string]*ModuleAttribution)
+	attribute := func(pkg *ssa.Package) *ModuleAttribution {
+		path := standardModule
+		if mod := modules[packagePath(pkg)]; mod != nil {
+			path = mod.Path
+		} else if pkg == nil {
+			path = syntheticPackage
+		}
+		if attribution[path] == nil {
+			attribution[path] = &ModuleAttribution{Path: path, Version: m.Versions[path]}
+		}
+		return attribution[path]
+	}
+
+	replaced, vendored := make(map[string]struct{}), make(map[string]struct{})
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		mod := pkg.Module
+		if mod == nil {
+			return
+		}
+		m.Versions[mod.Path] = mod.Version
+		if mod.Replace != nil {
+			replaced[mod.Path] = struct{}{}
+		}
+		for _, f := range pkg.GoFiles {
+			for _, dir := range vendorDirs {
+				if strings.HasPrefix(f, dir) {
+					vendored[mod.Path] = struct{}{}
+				}
+			}
+		}
+	})
+	m.Modules = len(m.Versions)
+	m.Replaced = maps.Keys(replaced)
+	slices.Sort(m.Replaced)
+	m.Vendored = maps.Keys(vendored)
+	slices.Sort(m.Vendored)
+
+	if prog != nil {
+		for fn := range ssautil.AllFunctions(prog) {
+			attribute(functionPackage(fn)).Functions++
+		}
+	}
+	if cg != nil {
+		for fn := range cg.Nodes {
+			if fn != nil {
+				attribute(functionPackage(fn)).CallGraphNodes++
+			}
+		}
+	}
+	if pta != nil {
+		for v := range pta.Queries {
+			attribute(valuePackage(v)).Queries++
+		}
+		for v := range pta.IndirectQueries {
+			attribute(valuePackage(v)).Queries++
+		}
+	}
+
+	for _, a := range attribution {
+		m.Attribution = append(m.Attribution, *a)
+	}
+	slices.SortFunc(m.Attribution, func(a, b ModuleAttribution) bool {
+		switch {
+		case a.Functions != b.Functions:
+			return a.Functions > b.Functions
+		case a.CallGraphNodes != b.CallGraphNodes:
+			return a.CallGraphNodes > b.CallGraphNodes
+		case a.Queries != b.Queries:
+			return a.Queries > b.Queries
+		}
+		return a.Path < b.Path
+	})
+
+	return m
+}
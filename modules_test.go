@@ -0,0 +1,127 @@
+package stamets
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestModuleMetrics(t *testing.T) {
+	main := &packages.Module{Path: "example.com/main", Main: true, Dir: "/src/main"}
+	dep := &packages.Module{Path: "example.com/dep", Version: "v1.0.0"}
+	replaced := &packages.Module{
+		Path:    "example.com/replaced",
+		Version: "v0.1.0",
+		Replace: &packages.Module{Path: "example.com/fork", Version: "v0.2.0"},
+	}
+
+	std := &packages.Package{ID: "fmt", PkgPath: "fmt"}
+	depPkg := &packages.Package{
+		ID:      "example.com/dep",
+		PkgPath: "example.com/dep",
+		Module:  dep,
+		GoFiles: []string{"/src/main/vendor/example.com/dep/dep.go"},
+	}
+	replacedPkg := &packages.Package{
+		ID:      "example.com/replaced",
+		PkgPath: "example.com/replaced",
+		Module:  replaced,
+		GoFiles: []string{"/mod/example.com/fork@v0.2.0/r.go"},
+	}
+	mainPkg := &packages.Package{
+		ID:      "example.com/main",
+		PkgPath: "example.com/main",
+		Module:  main,
+		GoFiles: []string{"/src/main/main.go"},
+		Imports: map[string]*packages.Package{
+			"fmt":                  std,
+			"example.com/dep":      depPkg,
+			"example.com/replaced": replacedPkg,
+		},
+	}
+
+	cg, _ := makeCallgraph(t)
+	m := GetModuleMetrics([]*packages.Package{mainPkg}, nil, cg, nil)
+
+	require.Equal(t, 3, m.Modules)
+	require.Equal(t, []string{"example.com/replaced"}, m.Replaced)
+	require.Equal(t, []string{"example.com/dep"}, m.Vendored)
+	require.Equal(t, map[string]string{
+		"example.com/main":     "",
+		"example.com/dep":      "v1.0.0",
+		"example.com/replaced": "v0.1.0",
+	}, m.Versions)
+
+	// Functions without packages are attributed to synthetic functions.
+	require.Equal(t, []ModuleAttribution{{
+		Path:           syntheticPackage,
+		CallGraphNodes: 7,
+	}}, m.Attribution)
+}
+
+func TestModuleMetricsLoaded(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main/go.mod": "module example.com/main\n\ngo 1.20\n\nrequire example.com/dep v1.0.0\n\nreplace example.com/dep => ../dep\n",
+		"main/main.go": `package main
+
+import "example.com/dep"
+
+func main() {
+	println(dep.New().Get())
+}
+`,
+		"dep/go.mod": "module example.com/dep\n\ngo 1.20\n",
+		"dep/dep.go": `package dep
+
+type T struct{ f *int }
+
+func New() *T { return &T{f: new(int)} }
+
+func (t *T) Get() *int { return t.f }
+`,
+	})
+	pkgs, prog := loadProgram(t, filepath.Join(dir, "main"), ".")
+
+	pta := AnalyzeProgram(prog, ProgramOptions{BuildCallGraph: true})
+	require.True(t, pta.Ok())
+
+	m := GetModuleMetrics(pkgs, prog, pta.Payload.CallGraph, pta.Payload)
+	require.True(t, m.Ok())
+	require.Equal(t, 2, m.Modules)
+	require.Equal(t, []string{"example.com/dep"}, m.Replaced)
+	require.Empty(t, m.Vendored)
+	require.Equal(t, map[string]string{
+		"example.com/main": "",
+		"example.com/dep":  "v1.0.0",
+	}, m.Versions)
+
+	attribution := make(map[string]ModuleAttribution)
+	for _, a := range m.Attribution {
+		attribution[a.Path] = a
+	}
+	require.Equal(t, ModuleAttribution{
+		Path:           "example.com/main",
+		Functions:      2,
+		CallGraphNodes: 2,
+		Queries:        attribution["example.com/main"].Queries,
+	}, attribution["example.com/main"])
+	require.NotZero(t, attribution["example.com/main"].Queries)
+	require.Equal(t, ModuleAttribution{
+		Path:           "example.com/dep",
+		Version:        "v1.0.0",
+		Functions:      3,
+		CallGraphNodes: 3,
+		Queries:        attribution["example.com/dep"].Queries,
+	}, attribution["example.com/dep"])
+	require.NotZero(t, attribution["example.com/dep"].Queries)
+
+	// Every query is attributed to a module.
+	queries := 0
+	for _, a := range m.Attribution {
+		queries += a.Queries
+	}
+	require.Equal(t, pta.Queries+pta.IndirectQueries, queries)
+	require.Contains(t, m.String(), "example.com/dep")
+}
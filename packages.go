@@ -1,59 +1,475 @@
 package stamets
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"golang.org/x/tools/go/packages"
 )
 
+// PackageCounts counts loaded packages, their files and lines of code.
+type PackageCounts struct {
+	Packages int
+	Files    int
+	Lines    int
+}
+
+func (c PackageCounts) add(o PackageCounts) PackageCounts {
+	return PackageCounts{
+		Packages: c.Packages + o.Packages,
+		Files:    c.Files + o.Files,
+		Lines:    c.Lines + o.Lines,
+	}
+}
+
+// PackagesMetrics aggregates important metrics about package loading.
+type PackagesMetrics struct {
+	BaseMetrics[[]*packages.Package]
+
+	// Time spent invoking the build system driver e.g., `go list`.
+	DriverDuration time.Duration
+	// Time spent parsing files. Files are parsed concurrently, so this is the time summed
+	// over all parsed files, which may exceed, and is not comparable to, the wall-clock duration.
+	ParseDuration time.Duration
+	// Wall-clock time from the end of the last driver invocation until loading completes.
+	// The loader does not time type-checking separately from parsing, which is interleaved
+	// with it, so this is the time spent parsing and type-checking packages.
+	TypeCheckDuration time.Duration
+
+	// Counts of loaded packages (including dependencies), split by
+	// whether they belong to the standard library, the main module,
+	// or to third-party modules. Lines are only counted for parsed files.
+	Standard   PackageCounts
+	MainModule PackageCounts
+	ThirdParty PackageCounts
+
+	// Errors encountered while loading packages and their dependencies.
+	Errors PackageErrors
+	// IDs of packages dropped due to errors when loading partially.
+	Dropped []string
+
+	// Metrics of every query pattern loaded separately, if computed by LoadPatterns.
+	Patterns []PatternMetrics
+	// Number of packages matched by more than one pattern, if computed by LoadPatterns.
+	SharedPackages int
+
+	// Query patterns, and whether packages with errors were tolerated
+	patterns []string
+	partial  bool
+}
+
+// PatternMetrics describes the packages matched by a query pattern,
+// when loading it separately from other patterns.
+type PatternMetrics struct {
+	Pattern string
+	// IDs of the packages matched by the pattern
+	Packages []string
+	// Time it took to load the pattern on its own
+	time.Duration
+
+	// Error produced when loading the pattern, if any
+	Err error
+	// Errors encountered while loading the packages matched by the pattern and their dependencies
+	Errors PackageErrors
+	// IDs of packages dropped due to errors when loading partially. Dropped packages
+	// are not attributed to the pattern.
+	Dropped []string
+}
+
+// PackageErrors groups package loading errors by kind, and then by the ID of
+// the package where they were encountered.
+type PackageErrors map[packages.ErrorKind]map[string][]packages.Error
+
+// Count returns the total number of errors.
+func (e PackageErrors) Count() int {
+	count := 0
+	for kind := range e {
+		count += e.CountKind(kind)
+	}
+	return count
+}
+
+// CountKind returns the number of errors of the given kind.
+func (e PackageErrors) CountKind(kind packages.ErrorKind) int {
+	count := 0
+	for _, errs := range e[kind] {
+		count += len(errs)
+	}
+	return count
+}
+
+// Total returns the counts of all loaded packages, files and lines.
+func (m PackagesMetrics) Total() PackageCounts {
+	return m.Standard.add(m.MainModule).add(m.ThirdParty)
+}
+
+func (m PackagesMetrics) String() string {
+	total := m.Total()
+	str := fmt.Sprintf(`
+PACKAGES METRICS
+- Duration: %f
+- Driver duration: %f
+- Parse duration: %f
+- Type-check duration (including parsing): %f
+- Number of packages: %d
+- Number of files: %d
+- Number of lines: %d
+Standard library:
+	- Packages: %d
+	- Files: %d
+	- Lines: %d
+Main module:
+	- Packages: %d
+	- Files: %d
+	- Lines: %d
+Third-party:
+	- Packages: %d
+	- Files: %d
+	- Lines: %d
+- Number of list errors: %d
+- Number of parse errors: %d
+- Number of type errors: %d
+- Number of unknown errors: %d
+- Number of dropped packages: %d
+- Number of patterns loaded separately: %d
+- Number of packages shared between patterns: %d
+`,
+		m.Duration.Seconds(),
+		m.DriverDuration.Seconds(),
+		m.ParseDuration.Seconds(),
+		m.TypeCheckDuration.Seconds(),
+		total.Packages,
+		total.Files,
+		total.Lines,
+		m.Standard.Packages,
+		m.Standard.Files,
+		m.Standard.Lines,
+		m.MainModule.Packages,
+		m.MainModule.Files,
+		m.MainModule.Lines,
+		m.ThirdParty.Packages,
+		m.ThirdParty.Files,
+		m.ThirdParty.Lines,
+		m.Errors.CountKind(packages.ListError),
+		m.Errors.CountKind(packages.ParseError),
+		m.Errors.CountKind(packages.TypeError),
+		m.Errors.CountKind(packages.UnknownError),
+		len(m.Dropped),
+		len(m.Patterns),
+		m.SharedPackages,
+	)
+
+	for _, p := range m.Patterns {
+		str += fmt.Sprintf("\t- Pattern %s: packages %d, duration %f, errors %d, dropped %d\n",
+			p.Pattern, len(p.Packages), p.Duration.Seconds(), p.Errors.Count(), len(p.Dropped))
+		if p.Err != nil {
+			str += fmt.Sprintf("\t\t- Error: %v\n", p.Err)
+		}
+	}
+	return str
+}
+
+// loadObserver instruments a package loading configuration to
+// collect per-phase timings and the number of lines in parsed files.
+type loadObserver struct {
+	mu     sync.Mutex
+	driver time.Duration
+	parse  time.Duration
+	lines  map[string]int
+
+	// Start of loading, and the time since then at which the last driver invocation ended
+	start     time.Time
+	driverEnd time.Duration
+}
+
+// instrument produces a copy of the configuration, where logging and parsing
+// are intercepted by the observer. The original logger and parser are still invoked.
+func (o *loadObserver) instrument(config *packages.Config) *packages.Config {
+	cfg := *config
+	// Module information is required to attribute packages to their origin.
+	cfg.Mode |= packages.NeedModule
+	o.start = time.Now()
+
+	logf := config.Logf
+	if debug, _ := strconv.ParseBool(os.Getenv("GOPACKAGESDEBUG")); logf == nil && debug {
+		// Preserve the default logger of the packages loader.
+		logf = log.Printf
+	}
+	cfg.Logf = func(format string, args ...interface{}) {
+		// The go command driver logs every invocation as "<duration> for <command>".
+		if format == "%s for %v" && len(args) > 0 {
+			if d, ok := args[0].(time.Duration); ok {
+				o.mu.Lock()
+				o.driver += d
+				o.driverEnd = time.Since(o.start)
+				o.mu.Unlock()
+			}
+		}
+		if logf != nil {
+			logf(format, args...)
+		}
+	}
+
+	parse := config.ParseFile
+	if parse == nil {
+		parse = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			const mode = parser.AllErrors | parser.ParseComments
+			return parser.ParseFile(fset, filename, src, mode)
+		}
+	}
+	cfg.ParseFile = func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+		start := time.Now()
+		f, err := parse(fset, filename, src)
+		d := time.Since(start)
+
+		lines := bytes.Count(src, []byte("\n"))
+		if len(src) > 0 && src[len(src)-1] != '\n' {
+			lines++
+		}
+
+		o.mu.Lock()
+		o.parse += d
+		o.lines[filename] = lines
+		o.mu.Unlock()
+		return f, err
+	}
+
+	return &cfg
+}
+
+// record populates the metrics with the observations collected while loading
+// the given packages.
+func (o *loadObserver) record(m PackagesMetrics, pkgs []*packages.Package) PackagesMetrics {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	m.DriverDuration = o.driver
+	m.ParseDuration = o.parse
+	if rest := m.Duration - o.driverEnd; rest > 0 {
+		m.TypeCheckDuration = rest
+	}
+
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		c := PackageCounts{
+			Packages: 1,
+			Files:    len(pkg.CompiledGoFiles),
+		}
+		for _, f := range pkg.CompiledGoFiles {
+			c.Lines += o.lines[f]
+		}
+
+		switch {
+		case pkg.Module == nil:
+			m.Standard = m.Standard.add(c)
+		case pkg.Module.Main:
+			m.MainModule = m.MainModule.add(c)
+		default:
+			m.ThirdParty = m.ThirdParty.add(c)
+		}
+	})
+
+	return m
+}
+
 // PackagesLoad loads packages according to the specified configuration and further
-// filters them with `query`. It performs additional filtering when the configuration includes
-// test packages.
-func PackagesLoad(config *packages.Config, query string) BaseMetrics[[]*packages.Package] {
+// filters them with the query `patterns`. It performs additional filtering when the configuration
+// includes test packages. Loading fails if any package, or any of its dependencies, has errors.
+func PackagesLoad(config *packages.Config, patterns ...string) PackagesMetrics {
+	return packagesLoad(config, false, patterns...)
+}
+
+// PackagesLoadWithTimeout loads packages according to the specified configuration within
+// the alloted time limit, and further filters them with the query `patterns`. It performs
+// additional filtering when the configuration includes test packages.
+func PackagesLoadWithTimeout(t time.Duration, config *packages.Config, patterns ...string) (PackagesMetrics, bool) {
+	return TaskWithTimeout(t, func() PackagesMetrics {
+		return PackagesLoad(config, patterns...)
+	})
+}
+
+// PackagesLoadPartial loads packages like PackagesLoad, but tolerates errors. Packages
+// that have errors, or depend on packages with errors, are dropped from the result
+// and recorded in the metrics. Loading only fails if no well-typed packages remain.
+func PackagesLoadPartial(config *packages.Config, patterns ...string) PackagesMetrics {
+	return packagesLoad(config, true, patterns...)
+}
+
+// PackagesLoadPartialWithTimeout loads packages like PackagesLoadPartial, within the alloted time limit.
+func PackagesLoadPartialWithTimeout(t time.Duration, config *packages.Config, patterns ...string) (PackagesMetrics, bool) {
+	return TaskWithTimeout(t, func() PackagesMetrics {
+		return PackagesLoadPartial(config, patterns...)
+	})
+}
+
+func packagesLoad(config *packages.Config, partial bool, patterns ...string) PackagesMetrics {
 	start := time.Now()
 
-	pkgs, err := packages.Load(config, query)
+	o := &loadObserver{lines: make(map[string]int)}
+	pkgs, err := packages.Load(o.instrument(config), patterns...)
 	if err != nil {
-		return BaseMetrics[[]*packages.Package]{
-			err: err,
+		return PackagesMetrics{
+			BaseMetrics: BaseMetrics[[]*packages.Package]{
+				err: err,
+			},
 		}
-	} else if packages.PrintErrors(pkgs) > 0 {
-		return BaseMetrics[[]*packages.Package]{
-			err: errors.New("errors encountered while loading packages"),
+	}
+
+	errs := getPackageErrors(pkgs)
+	var dropped []string
+	if count := errs.Count(); count > 0 && !partial {
+		return o.record(PackagesMetrics{
+			BaseMetrics: BaseMetrics[[]*packages.Package]{
+				Duration: time.Since(start),
+				err:      fmt.Errorf("%d errors encountered while loading packages", count),
+			},
+			Errors:   errs,
+			patterns: patterns,
+		}, pkgs)
+	} else if count > 0 {
+		pkgs, dropped = dropBrokenPackages(pkgs)
+		if len(pkgs) == 0 {
+			return o.record(PackagesMetrics{
+				BaseMetrics: BaseMetrics[[]*packages.Package]{
+					Duration: time.Since(start),
+					err:      fmt.Errorf("%d errors encountered while loading packages, and no well-typed packages remain", count),
+				},
+				Errors:   errs,
+				Dropped:  dropped,
+				patterns: patterns,
+				partial:  partial,
+			}, pkgs)
 		}
 	}
 	if config.Tests {
-		// Deduplicate packages that have test functions (such packages are
-		// returned twice, once with no tests and once with tests. We discard
-		// the package without tests.) This prevents duplicate versions of the
-		// same types, functions, ssa values, etc., which can be very confusing
-		// when debugging.
-		packageIDs := map[string]bool{}
-		for _, pkg := range pkgs {
-			packageIDs[pkg.ID] = true
-		}
-
-		filteredPkgs := []*packages.Package{}
-		for _, pkg := range pkgs {
-			if !packageIDs[fmt.Sprintf("%s [%s.test]", pkg.ID, pkg.ID)] {
-				filteredPkgs = append(filteredPkgs, pkg)
-			}
+		pkgs = dedupTestPackages(pkgs)
+	}
+
+	return o.record(PackagesMetrics{
+		BaseMetrics: BaseMetrics[[]*packages.Package]{
+			Payload:  pkgs,
+			Duration: time.Since(start),
+		},
+		Errors:   errs,
+		Dropped:  dropped,
+		patterns: patterns,
+		partial:  partial,
+	}, pkgs)
+}
+
+// LoadPatterns loads every query pattern of the metrics separately with the given configuration,
+// which should be the configuration used for loading, to attribute packages to patterns and measure
+// the load time of each pattern on its own. Like the original load, packages with errors are dropped
+// when loading partially. Patterns are only loaded if more than one pattern was given, and every
+// pattern is loaded in full, such that the total load time is roughly doubled.
+func (m PackagesMetrics) LoadPatterns(config *packages.Config) PackagesMetrics {
+	if len(m.patterns) < 2 {
+		return m
+	}
+
+	m.Patterns = make([]PatternMetrics, 0, len(m.patterns))
+	matches := make(map[string]int)
+	for _, pattern := range m.patterns {
+		p := loadPattern(config, pattern, m.partial)
+		for _, id := range p.Packages {
+			matches[id]++
+		}
+		m.Patterns = append(m.Patterns, p)
+	}
+
+	m.SharedPackages = 0
+	for _, count := range matches {
+		if count > 1 {
+			m.SharedPackages++
 		}
-		pkgs = filteredPkgs
 	}
-	return BaseMetrics[[]*packages.Package]{
-		Payload:  pkgs,
+	return m
+}
+
+// loadPattern loads a single query pattern, attributing the packages it matches to it.
+func loadPattern(config *packages.Config, pattern string, partial bool) PatternMetrics {
+	start := time.Now()
+	pkgs, err := packages.Load(config, pattern)
+	p := PatternMetrics{
+		Pattern:  pattern,
 		Duration: time.Since(start),
+		Err:      err,
+	}
+	if err != nil {
+		return p
+	}
+
+	p.Errors = getPackageErrors(pkgs)
+	if partial && p.Errors.Count() > 0 {
+		pkgs, p.Dropped = dropBrokenPackages(pkgs)
+	}
+	if config.Tests {
+		pkgs = dedupTestPackages(pkgs)
+	}
+	for _, pkg := range pkgs {
+		p.Packages = append(p.Packages, pkg.ID)
 	}
+	return p
 }
 
-// PackagesLoadWithTimeout loads packages according to the specified configuration within
-// the alloted time limit, and further filters them with `query`. It performs additional
-// filtering when the configuration includes test packages.
-func PackagesLoadWithTimeout(t time.Duration, config *packages.Config, query string) (BaseMetrics[[]*packages.Package], bool) {
-	return TaskWithTimeout(t, func() BaseMetrics[[]*packages.Package] {
-		return PackagesLoad(config, query)
+// dedupTestPackages deduplicates packages that have test functions (such packages are
+// returned twice, once with no tests and once with tests. We discard
+// the package without tests.) This prevents duplicate versions of the
+// same types, functions, ssa values, etc., which can be very confusing
+// when debugging.
+func dedupTestPackages(pkgs []*packages.Package) []*packages.Package {
+	packageIDs := map[string]bool{}
+	for _, pkg := range pkgs {
+		packageIDs[pkg.ID] = true
+	}
+
+	filteredPkgs := []*packages.Package{}
+	for _, pkg := range pkgs {
+		if !packageIDs[fmt.Sprintf("%s [%s.test]", pkg.ID, pkg.ID)] {
+			filteredPkgs = append(filteredPkgs, pkg)
+		}
+	}
+	return filteredPkgs
+}
+
+// getPackageErrors collects the errors of all the given packages and their dependencies.
+func getPackageErrors(pkgs []*packages.Package) PackageErrors {
+	errs := make(PackageErrors)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		for _, err := range pkg.Errors {
+			if errs[err.Kind] == nil {
+				errs[err.Kind] = make(map[string][]packages.Error)
+			}
+			errs[err.Kind][pkg.ID] = append(errs[err.Kind][pkg.ID], err)
+		}
 	})
+	return errs
+}
+
+// dropBrokenPackages removes all packages that have errors, or depend on packages with
+// errors. It returns the remaining packages, and the IDs of the dropped packages.
+func dropBrokenPackages(pkgs []*packages.Package) (well []*packages.Package, dropped []string) {
+	broken := make(map[*packages.Package]bool)
+	packages.Visit(pkgs, nil, func(pkg *packages.Package) {
+		broken[pkg] = len(pkg.Errors) > 0
+		for _, imp := range pkg.Imports {
+			broken[pkg] = broken[pkg] || broken[imp]
+		}
+	})
+
+	for _, pkg := range pkgs {
+		if broken[pkg] {
+			dropped = append(dropped, pkg.ID)
+		} else {
+			well = append(well, pkg)
+		}
+	}
+	return well, dropped
 }
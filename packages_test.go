@@ -0,0 +1,129 @@
+package stamets
+
+import (
+	"go/token"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+)
+
+func TestLoadObserver(t *testing.T) {
+	o := &loadObserver{lines: make(map[string]int)}
+	cfg := o.instrument(&packages.Config{})
+	require.NotZero(t, cfg.Mode&packages.NeedModule)
+
+	cfg.Logf("%s for %v", time.Second, "go list")
+	cfg.Logf("unrelated %d", 1)
+
+	_, err := cfg.ParseFile(token.NewFileSet(), "a.go", []byte("package a\n\nvar x = 1\n"))
+	require.NoError(t, err)
+	_, err = cfg.ParseFile(token.NewFileSet(), "b.go", []byte("package a\nvar y = 1"))
+	require.NoError(t, err)
+
+	std := &packages.Package{ID: "fmt", CompiledGoFiles: []string{"fmt.go"}}
+	dep := &packages.Package{
+		ID:              "dep",
+		Module:          &packages.Module{Path: "dep"},
+		CompiledGoFiles: []string{"b.go"},
+		Imports:         map[string]*packages.Package{"fmt": std},
+	}
+	main := &packages.Package{
+		ID:              "a",
+		Module:          &packages.Module{Path: "a", Main: true},
+		CompiledGoFiles: []string{"a.go"},
+		Imports:         map[string]*packages.Package{"fmt": std, "dep": dep},
+	}
+
+	m := o.record(PackagesMetrics{
+		BaseMetrics: BaseMetrics[[]*packages.Package]{
+			Duration: 2 * time.Second,
+		},
+	}, []*packages.Package{main})
+
+	require.Equal(t, time.Second, m.DriverDuration)
+	require.NotZero(t, m.ParseDuration)
+	require.InDelta(t, 2*time.Second, m.TypeCheckDuration, float64(time.Second))
+	require.Equal(t, PackageCounts{Packages: 1, Files: 1}, m.Standard)
+	require.Equal(t, PackageCounts{Packages: 1, Files: 1, Lines: 3}, m.MainModule)
+	require.Equal(t, PackageCounts{Packages: 1, Files: 1, Lines: 2}, m.ThirdParty)
+	require.Equal(t, PackageCounts{Packages: 3, Files: 3, Lines: 5}, m.Total())
+}
+
+func TestPackageErrors(t *testing.T) {
+	listErr := packages.Error{Msg: "no Go files", Kind: packages.ListError}
+	typeErr := packages.Error{Msg: "undefined: x", Kind: packages.TypeError}
+
+	broken := &packages.Package{ID: "broken", Errors: []packages.Error{typeErr, typeErr}}
+	empty := &packages.Package{ID: "empty", Errors: []packages.Error{listErr}}
+	dependent := &packages.Package{
+		ID:      "dependent",
+		Imports: map[string]*packages.Package{"broken": broken},
+	}
+	well := &packages.Package{ID: "well"}
+
+	pkgs := []*packages.Package{dependent, empty, well}
+	errs := getPackageErrors(pkgs)
+	require.Equal(t, 3, errs.Count())
+	require.Equal(t, 2, errs.CountKind(packages.TypeError))
+	require.Equal(t, 1, errs.CountKind(packages.ListError))
+	require.Zero(t, errs.CountKind(packages.ParseError))
+	require.Equal(t, PackageErrors{
+		packages.ListError: {"empty": {listErr}},
+		packages.TypeError: {"broken": {typeErr, typeErr}},
+	}, errs)
+
+	remaining, dropped := dropBrokenPackages(pkgs)
+	require.Equal(t, []*packages.Package{well}, remaining)
+	require.Equal(t, []string{"dependent", "empty"}, dropped)
+}
+
+// writeFiles writes the given files to a temporary directory, returning the directory.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+	}
+	return dir
+}
+
+func TestLoadPatterns(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"go.mod":     "module example.com/m\n\ngo 1.20\n",
+		"a/a.go":     "package a\n\nfunc A() {}\n",
+		"b/b.go":     "package b\n\nimport \"example.com/m/a\"\n\nfunc B() { a.A() }\n",
+		"bad/bad.go": "package bad\n\nvar x int = \"\"\n",
+	})
+	config := &packages.Config{Mode: packages.LoadAllSyntax, Dir: dir}
+
+	// Patterns are only loaded separately on request.
+	m := PackagesLoadPartial(config, "./a", "./...")
+	require.True(t, m.Ok())
+	require.NotZero(t, m.TypeCheckDuration)
+	require.Less(t, m.TypeCheckDuration, m.Duration)
+	require.Nil(t, m.Patterns)
+
+	m = m.LoadPatterns(config)
+	require.Len(t, m.Patterns, 2)
+	require.Equal(t, 1, m.SharedPackages)
+
+	a, all := m.Patterns[0], m.Patterns[1]
+	require.Equal(t, "./a", a.Pattern)
+	require.Equal(t, []string{"example.com/m/a"}, a.Packages)
+	require.Zero(t, a.Errors.Count())
+	require.NoError(t, a.Err)
+
+	// Broken packages are dropped, like when loading partially.
+	require.ElementsMatch(t, []string{"example.com/m/a", "example.com/m/b"}, all.Packages)
+	require.Equal(t, []string{"example.com/m/bad"}, all.Dropped)
+	require.Equal(t, 1, all.Errors.CountKind(packages.TypeError))
+	require.Contains(t, m.String(), "- Number of packages shared between patterns: 1")
+
+	// A single pattern is not loaded again.
+	require.Nil(t, PackagesLoad(config, "./a").LoadPatterns(config).Patterns)
+}
@@ -0,0 +1,200 @@
+package stamets
+
+import (
+	"errors"
+	"fmt"
+	"go/token"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/rta"
+	"golang.org/x/tools/go/ssa"
+)
+
+// CallSitePrecision describes the callees of a dynamic call site under every call graph algorithm.
+type CallSitePrecision struct {
+	Site     ssa.CallInstruction
+	Caller   *ssa.Function
+	Position token.Position
+
+	// Number of callees via Class Hierarchy Analysis, Rapid Type Analysis,
+	// Variable Type Analysis and the points-to analysis
+	CHA, RTA, VTA, PTA int
+}
+
+// Gain is the number of callees via Class Hierarchy Analysis which are removed by the points-to analysis.
+func (s CallSitePrecision) Gain() int {
+	return s.CHA - s.PTA
+}
+
+func (s CallSitePrecision) String() string {
+	return fmt.Sprintf("%s in %s: CHA %d, RTA %d, VTA %d, PTA %d", s.Position, s.Caller, s.CHA, s.RTA, s.VTA, s.PTA)
+}
+
+// AlgorithmPrecision summarizes the callees of dynamic call sites under a call graph algorithm.
+type AlgorithmPrecision struct {
+	// Number of callees, summed over all call sites
+	Callees int
+	// Number of call sites with a single callee
+	SingleCallee int
+	// Distribution of the ratio of callees via Class Hierarchy Analysis removed by the algorithm,
+	// over the call sites with callees via Class Hierarchy Analysis. Ratios are negative at call
+	// sites where the algorithm finds callees missed by Class Hierarchy Analysis.
+	Reduction RatioDistribution
+}
+
+func (p AlgorithmPrecision) String() string {
+	return fmt.Sprintf("%d callees, %d single-callee sites, reduction %s", p.Callees, p.SingleCallee, p.Reduction)
+}
+
+// PrecisionGain compares the callees of the dynamic call sites reachable in the PTA call graph, under
+// Class Hierarchy Analysis, Rapid Type Analysis, Variable Type Analysis and the points-to analysis.
+type PrecisionGain struct {
+	// Number of dynamic call sites
+	Sites int
+
+	CHA, RTA, VTA, PTA AlgorithmPrecision
+
+	// Call sites where the points-to analysis removed the most callees, in descending order
+	Gains []CallSitePrecision
+}
+
+func (g PrecisionGain) String() string {
+	str := fmt.Sprintf(`
+PRECISION GAIN
+- Number of dynamic call sites: %d
+- CHA: %s
+- RTA: %s
+- VTA: %s
+- PTA: %s
+Call sites with the largest PTA gain:
+`,
+		g.Sites,
+		g.CHA,
+		g.RTA,
+		g.VTA,
+		g.PTA,
+	)
+	for _, s := range g.Gains {
+		str += "\t- " + s.String() + "\n"
+	}
+	return str
+}
+
+// calleeCounts counts the callees of every call site in a call graph.
+func calleeCounts(cg *callgraph.Graph) map[ssa.CallInstruction]int {
+	counts := make(map[ssa.CallInstruction]int)
+	for _, n := range cg.Nodes {
+		// Call graphs may include several edges to the same callee.
+		callees := make(map[ssa.CallInstruction]map[*ssa.Function]struct{})
+		for _, e := range n.Out {
+			if e.Site == nil {
+				continue
+			}
+			if callees[e.Site] == nil {
+				callees[e.Site] = make(map[*ssa.Function]struct{})
+			}
+			callees[e.Site][e.Callee.Func] = struct{}{}
+		}
+		for site, fns := range callees {
+			counts[site] = len(fns)
+		}
+	}
+	return counts
+}
+
+// PrecisionGain computes how much the points-to analysis improves on cheaper call graph algorithms,
+// by comparing the number of callees of the dynamic call sites in the functions of the PTA call graph.
+// The program must be the analyzed program, and the PTA call graph must be constructed. At most n call
+// sites with the largest gain are reported, and n must not be negative. Only the call graph of the PTA result is required, such that
+// the results of the unification-based points-to analysis are supported.
+//
+// The algorithms do not start from the same entry points. Rapid Type Analysis starts from the entry
+// points of the PTA call graph, whereas Class Hierarchy Analysis and Variable Type Analysis consider
+// every function of the program. Callees of a call site which are only reachable from other entry
+// points are therefore counted by CHA and VTA, but not by RTA and PTA.
+func (m PTAMetrics) PrecisionGain(prog *ssa.Program, n int) (PrecisionGain, error) {
+	if n < 0 {
+		return PrecisionGain{}, fmt.Errorf("invalid number of reported call sites: %d", n)
+	}
+	if m.Payload == nil || m.Payload.CallGraph == nil {
+		return PrecisionGain{}, errors.New("the PTA result does not include a call graph")
+	}
+	cg := m.Payload.CallGraph
+
+	var roots []*ssa.Function
+	for _, e := range cg.Root.Out {
+		roots = append(roots, e.Callee.Func)
+	}
+	chaCounts := calleeCounts(chaCallGraph(prog))
+	vtaCounts := calleeCounts(vtaCallGraph(prog))
+	ptaCounts := calleeCounts(cg)
+	rtaCounts := map[ssa.CallInstruction]int{}
+	if res := rta.Analyze(roots, true); res != nil {
+		rtaCounts = calleeCounts(res.CallGraph)
+	}
+
+	var sites []CallSitePrecision
+	for fn := range cg.Nodes {
+		if fn == nil {
+			continue
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				site, ok := instr.(ssa.CallInstruction)
+				if !ok || site.Common().StaticCallee() != nil {
+					continue
+				}
+				if _, ok := site.Common().Value.(*ssa.Builtin); ok {
+					continue
+				}
+				sites = append(sites, CallSitePrecision{
+					Site:     site,
+					Caller:   fn,
+					Position: prog.Fset.Position(site.Pos()),
+					CHA:      chaCounts[site],
+					RTA:      rtaCounts[site],
+					VTA:      vtaCounts[site],
+					PTA:      ptaCounts[site],
+				})
+			}
+		}
+	}
+
+	g := PrecisionGain{Sites: len(sites)}
+	for _, alg := range []struct {
+		p       *AlgorithmPrecision
+		callees func(CallSitePrecision) int
+	}{
+		{&g.CHA, func(s CallSitePrecision) int { return s.CHA }},
+		{&g.RTA, func(s CallSitePrecision) int { return s.RTA }},
+		{&g.VTA, func(s CallSitePrecision) int { return s.VTA }},
+		{&g.PTA, func(s CallSitePrecision) int { return s.PTA }},
+	} {
+		var ratios []float64
+		for _, s := range sites {
+			k := alg.callees(s)
+			alg.p.Callees += k
+			if k == 1 {
+				alg.p.SingleCallee++
+			}
+			if s.CHA > 0 {
+				ratios = append(ratios, 1-float64(k)/float64(s.CHA))
+			}
+		}
+		alg.p.Reduction = makeRatioDistribution(ratios)
+	}
+
+	slices.SortFunc(sites, func(a, b CallSitePrecision) bool {
+		if a.Gain() != b.Gain() {
+			return a.Gain() > b.Gain()
+		}
+		return positionLess(a.Position, b.Position)
+	})
+	if len(sites) > n {
+		sites = sites[:n]
+	}
+	g.Gains = sites
+
+	return g, nil
+}
@@ -0,0 +1,75 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrecisionGain(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"main": `package main
+
+type I interface{ M() }
+
+type A struct{}
+
+func (A) M() {}
+
+type B struct{}
+
+func (B) M() {}
+
+type C struct{}
+
+func (C) M() {}
+
+func call(i I) { i.M() }
+
+func main() {
+	var a, b I = A{}, B{}
+	call(a)
+	b.M()
+	fs := []func(){func() {}}
+	fs[0]()
+}
+`,
+	}, "main")
+
+	m := AnalyzeProgram(prog, ProgramOptions{BuildCallGraph: true})
+	require.True(t, m.Ok())
+
+	g, err := m.PrecisionGain(prog, 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, g.Sites)
+
+	// Class Hierarchy Analysis includes the methods of *A, *B and *C. Rapid Type Analysis
+	// excludes C, which is never converted to an interface, and only A flows to call.
+	require.Len(t, g.Gains, 1)
+	gain := g.Gains[0]
+	require.Equal(t, "call", gain.Caller.Name())
+	require.Equal(t, 6, gain.CHA)
+	require.Equal(t, 4, gain.RTA)
+	require.Equal(t, 1, gain.VTA)
+	require.Equal(t, 1, gain.PTA)
+	require.Equal(t, 5, gain.Gain())
+
+	require.Zero(t, g.CHA.SingleCallee)
+	require.Equal(t, 3, g.PTA.SingleCallee)
+	require.Equal(t, 3, g.PTA.Callees)
+	require.InDelta(t, 5.0/6, g.PTA.Reduction.Max, 1e-9)
+	require.Zero(t, g.CHA.Reduction.Max)
+	require.Contains(t, g.String(), "- Number of dynamic call sites: 3")
+
+	_, err = PTAMetrics{}.PrecisionGain(prog, 1)
+	require.Error(t, err)
+	_, err = m.PrecisionGain(prog, -1)
+	require.Error(t, err)
+
+	// Only the call graph is required.
+	m = AnalyzeProgram(prog, ProgramOptions{Unification: true})
+	require.True(t, m.Ok())
+	g, err = m.PrecisionGain(prog, 1)
+	require.NoError(t, err)
+	require.Equal(t, 3, g.Sites)
+}
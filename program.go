@@ -0,0 +1,255 @@
+package stamets
+
+import (
+	"errors"
+	"go/types"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// QueryScope determines which values are queried when analyzing a program.
+type QueryScope int
+
+const (
+	// ScopeAll queries values in all packages.
+	ScopeAll QueryScope = iota
+	// ScopeMainModule queries values in the packages of the main module.
+	ScopeMainModule
+	// ScopePackages queries values in the named packages.
+	ScopePackages
+)
+
+// ProgramOptions configures the points-to analysis of an SSA program.
+type ProgramOptions struct {
+	// Scope of queried values
+	Scope QueryScope
+	// Path of the main module, required by ScopeMainModule
+	Module string
+	// Paths of the queried packages, required by ScopePackages
+	Packages []string
+
+	// Analyze test main packages, as generated when loading packages with tests,
+	// instead of main packages.
+	Tests bool
+	// Analyze the program from a synthetic main package, calling every exported function
+	// and method of the packages in scope with fresh arguments, instead of main packages.
+	// Useful for library packages without main packages.
+	Synthetic bool
+	// Skip indirect queries
+	SkipIndirectQueries bool
+	// Handle reflection soundly
+	Reflection bool
+	// Construct the call graph
+	BuildCallGraph bool
+	// Collect metrics about the constraint system, like AnalyzeConstraints
+	Constraints bool
+	// Measure the retained size of the PTA result, like MeasureRetained
+	MeasureRetained bool
+	// Run the unification-based points-to analysis instead, like AnalyzeUnification.
+	// It cannot be combined with Reflection or Constraints. Metrics requiring the
+	// points-to sets of queries e.g., LabelKindMetrics, are not supported.
+	Unification bool
+}
+
+// inScope checks whether values in the package with the given path are queried.
+func (opts ProgramOptions) inScope(path string) bool {
+	switch opts.Scope {
+	case ScopeMainModule:
+		return path == opts.Module || strings.HasPrefix(path, opts.Module+"/")
+	case ScopePackages:
+		return slices.Contains(opts.Packages, path)
+	}
+	return true
+}
+
+// analyzer selects the points-to analysis to run on the configurations derived from a program.
+func (opts ProgramOptions) analyzer() func(*pointer.Config) PTAMetrics {
+	analyze := Analyze
+	switch {
+	case opts.Unification:
+		analyze = AnalyzeUnification
+	case opts.Constraints:
+		analyze = AnalyzeConstraints
+	}
+
+	if opts.MeasureRetained {
+		return func(config *pointer.Config) PTAMetrics {
+			return MeasureRetained(analyze, config)
+		}
+	}
+	return analyze
+}
+
+// isTestMain checks whether a package is a test main package, as generated when
+// loading packages with tests.
+func isTestMain(pkg *ssa.Package) bool {
+	return strings.HasSuffix(pkg.Pkg.Path(), ".test")
+}
+
+// errNoProgram is returned when analyzing a nil SSA program.
+var errNoProgram = errors.New("no SSA program to analyze")
+
+// programMains discovers the main packages of a program, or the test main packages if
+// tests are analyzed. Synthetic main packages are excluded.
+func programMains(prog *ssa.Program, tests bool) (mains []*ssa.Package) {
+	for _, pkg := range ssautil.MainPackages(prog.AllPackages()) {
+		if !isSynthetic(pkg) && isTestMain(pkg) == tests {
+			mains = append(mains, pkg)
+		}
+	}
+	return mains
+}
+
+// programConfig constructs a points-to analysis configuration for a program, by discovering
+// its main packages and adding queries for every pointer-like value in scope. It also returns
+// the number of synthetic entry calls, if analyzing from a synthetic main package.
+func programConfig(prog *ssa.Program, opts ProgramOptions) (*pointer.Config, int, error) {
+	mains, calls, err := programEntries(prog, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	return pointerConfig(prog, mains, opts), calls, nil
+}
+
+// programEntries discovers the main packages of a program, or synthesizes a main package
+// calling the entry points of the packages in scope. It also returns the number of synthetic
+// entry calls, if a main package was synthesized.
+func programEntries(prog *ssa.Program, opts ProgramOptions) ([]*ssa.Package, int, error) {
+	if prog == nil {
+		return nil, 0, errNoProgram
+	}
+	if err := opts.validate(); err != nil {
+		return nil, 0, err
+	}
+
+	if opts.Synthetic {
+		var entries []entryPoint
+		for _, pkg := range prog.AllPackages() {
+			if pkg.Pkg.Name() != "main" && opts.inScope(packagePath(pkg)) {
+				entries = append(entries, entryPoints(pkg)...)
+			}
+		}
+
+		main, calls, err := synthesizeMain(prog, entries)
+		if err != nil {
+			return nil, 0, err
+		}
+		return []*ssa.Package{main}, calls, nil
+	}
+
+	mains := programMains(prog, opts.Tests)
+	if len(mains) == 0 {
+		return nil, 0, errors.New("no main packages found")
+	}
+	return mains, 0, nil
+}
+
+// validate checks whether the options are consistent.
+func (opts ProgramOptions) validate() error {
+	switch {
+	case opts.Scope == ScopeMainModule && opts.Module == "":
+		return errors.New("querying the main module requires its path")
+	case opts.Scope == ScopePackages && len(opts.Packages) == 0:
+		return errors.New("querying packages requires their paths")
+	case opts.Unification && opts.Reflection:
+		return errors.New("the unification-based analysis does not handle reflection")
+	case opts.Unification && opts.Constraints:
+		return errors.New("the unification-based analysis has no constraint system")
+	}
+	return nil
+}
+
+// pointerConfig constructs a points-to analysis configuration for the given main packages,
+// adding queries for every pointer-like value in scope.
+func pointerConfig(prog *ssa.Program, mains []*ssa.Package, opts ProgramOptions) *pointer.Config {
+	config := &pointer.Config{
+		Mains:          mains,
+		Reflection:     opts.Reflection,
+		BuildCallGraph: opts.BuildCallGraph,
+	}
+	addQueries(config, prog, opts)
+	return config
+}
+
+// addQueries adds a query for every pointer-like value in scope, and an indirect
+// query for every pointer to a pointer-like value in scope.
+func addQueries(config *pointer.Config, prog *ssa.Program, opts ProgramOptions) {
+	add := func(v ssa.Value) {
+		t := v.Type()
+		if pointer.CanPoint(t) {
+			config.AddQuery(v)
+		}
+		if ptr, ok := t.Underlying().(*types.Pointer); ok && !opts.SkipIndirectQueries && pointer.CanPoint(ptr.Elem()) {
+			config.AddIndirectQuery(v)
+		}
+	}
+
+	for fn := range ssautil.AllFunctions(prog) {
+		// The pointer analysis does not support generic function bodies.
+		if fn.TypeParams().Len() > 0 && len(fn.TypeArgs()) == 0 {
+			continue
+		}
+		if pkg := functionPackage(fn); (pkg != nil && isSynthetic(pkg)) || !opts.inScope(packagePath(pkg)) {
+			continue
+		}
+
+		for _, p := range fn.Params {
+			add(p)
+		}
+		for _, fv := range fn.FreeVars {
+			add(fv)
+		}
+		for _, b := range fn.Blocks {
+			for _, instr := range b.Instrs {
+				switch v := instr.(type) {
+				case *ssa.Range:
+					// Range iterators have opaque types.
+				case ssa.Value:
+					add(v)
+				}
+			}
+		}
+	}
+
+	for _, pkg := range prog.AllPackages() {
+		if isSynthetic(pkg) || !opts.inScope(packagePath(pkg)) {
+			continue
+		}
+		for _, mem := range pkg.Members {
+			if g, ok := mem.(*ssa.Global); ok {
+				add(g)
+			}
+		}
+	}
+}
+
+// AnalyzeProgram runs the points-to analysis on an SSA program, collecting metrics like Analyze.
+// The analysis configuration is derived from the program, by discovering its main packages, and
+// querying every pointer-like value in the scope specified by the options.
+func AnalyzeProgram(prog *ssa.Program, opts ProgramOptions) PTAMetrics {
+	config, calls, err := programConfig(prog, opts)
+	if err != nil {
+		return PTAMetrics{
+			BaseMetrics: BaseMetrics[*pointer.Result]{
+				err: err,
+			},
+		}
+	}
+
+	m := opts.analyzer()(config)
+	m.SyntheticEntryCalls = calls
+	return m
+}
+
+// AnalyzeProgramWithTimeout runs the points-to analysis on an SSA program like AnalyzeProgram,
+// in the alloted time limit.
+func AnalyzeProgramWithTimeout(t time.Duration, prog *ssa.Program, opts ProgramOptions) (PTAMetrics, bool) {
+	return TaskWithTimeout(t, func() PTAMetrics {
+		return AnalyzeProgram(prog, opts)
+	})
+}
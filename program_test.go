@@ -0,0 +1,100 @@
+package stamets
+
+import (
+	"go/types"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestProgramOptionsInScope(t *testing.T) {
+	opts := ProgramOptions{}
+	require.True(t, opts.inScope("fmt"))
+	require.True(t, opts.inScope(""))
+
+	opts = ProgramOptions{Scope: ScopeMainModule, Module: "example.com/mod"}
+	require.True(t, opts.inScope("example.com/mod"))
+	require.True(t, opts.inScope("example.com/mod/internal/x"))
+	require.True(t, opts.inScope("example.com/mod/x_test"))
+	require.False(t, opts.inScope("example.com/module"))
+	require.False(t, opts.inScope("fmt"))
+
+	opts = ProgramOptions{Scope: ScopePackages, Packages: []string{"fmt", "example.com/mod/x"}}
+	require.True(t, opts.inScope("fmt"))
+	require.True(t, opts.inScope("example.com/mod/x"))
+	require.False(t, opts.inScope("example.com/mod"))
+}
+
+func TestProgramConfigErrors(t *testing.T) {
+	_, _, err := programConfig(nil, ProgramOptions{Scope: ScopeMainModule})
+	require.Error(t, err)
+
+	_, _, err = programConfig(nil, ProgramOptions{Scope: ScopePackages})
+	require.Error(t, err)
+
+	m := AnalyzeProgram(nil, ProgramOptions{Scope: ScopePackages})
+	require.False(t, m.Ok())
+
+	m = AnalyzeProgram(nil, ProgramOptions{})
+	_, err = m.Unpack()
+	require.ErrorIs(t, err, errNoProgram)
+	_, err = AnalyzeBinaries(nil, ProgramOptions{}, ParallelOptions{}).Unpack()
+	require.ErrorIs(t, err, errNoProgram)
+	_, err = AnalyzeAdaptive(nil, ProgramOptions{}, time.Second).Unpack()
+	require.ErrorIs(t, err, errNoProgram)
+}
+
+func TestAnalyzeProgram(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"lib": `package lib
+
+type I interface{ M() *int }
+type T struct{ f *int }
+
+func (t *T) M() *int { return t.f }
+
+func New() I { return &T{f: new(int)} }
+`,
+		"main": `package main
+
+import "lib"
+
+func main() {
+	i := lib.New()
+	println(i.M())
+}
+`,
+	}, "lib", "main")
+
+	m := AnalyzeProgram(prog, ProgramOptions{BuildCallGraph: true})
+	require.True(t, m.Ok())
+	require.NotZero(t, m.Queries)
+	require.NotZero(t, m.IndirectQueries)
+	require.Len(t, m.Payload.Queries, m.Queries)
+	require.NotZero(t, m.PointsToSetSizeMax)
+
+	// The main function is called from the root, and calls the method dynamically.
+	main := prog.ImportedPackage("main").Func("main")
+	method := prog.FuncValue(prog.ImportedPackage("lib").Type("T").Type().(*types.Named).Method(0))
+	cg := m.Payload.CallGraph
+	require.NotNil(t, cg.Nodes[main])
+	require.Contains(t, callees(cg.Root), main)
+	require.Contains(t, callees(cg.Nodes[main]), method)
+
+	// Queries are restricted to the packages in scope.
+	lib := AnalyzeProgram(prog, ProgramOptions{Scope: ScopePackages, Packages: []string{"lib"}})
+	require.True(t, lib.Ok())
+	require.Less(t, lib.Queries, m.Queries)
+	require.Nil(t, lib.Payload.CallGraph)
+}
+
+// callees lists the callees of a call graph node.
+func callees(n *callgraph.Node) (fns []*ssa.Function) {
+	for _, e := range n.Out {
+		fns = append(fns, e.Callee.Func)
+	}
+	return fns
+}
@@ -1,18 +1,47 @@
 package stamets
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"golang.org/x/exp/slices"
 	"golang.org/x/tools/go/callgraph"
 	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
 )
 
+// AnalysisKind describes the points-to analysis producing a result.
+type AnalysisKind int
+
+const (
+	// AnalysisInclusion is the inclusion-based (Andersen-style) analysis of golang.org/x/tools/go/pointer.
+	AnalysisInclusion AnalysisKind = iota
+	// AnalysisUnification is the unification-based (Steensgaard-style) analysis, as run by AnalyzeUnification.
+	AnalysisUnification
+)
+
+// analysisKinds lists the names of points-to analyses, as printed in PTA metrics.
+var analysisKinds = map[AnalysisKind]string{
+	AnalysisInclusion:   "inclusion",
+	AnalysisUnification: "unification",
+}
+
+func (k AnalysisKind) String() string {
+	return analysisKinds[k]
+}
+
 // PTAMetrics aggregates important metrics about the points-to analysis.
 type PTAMetrics struct {
 	BaseMetrics[*pointer.Result]
 
+	// Points-to analysis producing the result
+	Analysis AnalysisKind
+
+	// Number of functions and instructions in the analyzed SSA program
+	Functions    int
+	Instructions int
+
 	Queries         int
 	IndirectQueries int
 
@@ -24,12 +53,58 @@ type PTAMetrics struct {
 	PointsToSetSizeP90  int
 	PointsToSetSizeP99  int
 	PointsToSetSizeMode int
+
+	// Points-to set sizes of indirect queries i.e., of the values pointed to
+	// by queried pointers.
+	IndirectPointsToSetSizeMax  int
+	IndirectPointsToSetSizeP50  int
+	IndirectPointsToSetSizeP90  int
+	IndirectPointsToSetSizeP99  int
+	IndirectPointsToSetSizeMode int
+
+	// Number of calls in the synthetic root function, when analyzing
+	// a program without main packages via synthetic entry points.
+	SyntheticEntryCalls int
+
+	// Number of warnings about unsound analysis results
+	Warnings int
+	// Number of warnings per message template
+	WarningCategories map[string]int
+	// Source positions triggering the most warnings, in descending order
+	WarningPositions []WarningPosition
+
+	// Approximate size of the PTA result in bytes, if measured by MeasureRetained as the growth
+	// of the live heap over the analysis. The result retains the internal state of the analysis,
+	// which is included. Concurrent allocations may skew the measurement.
+	RetainedBytes uint64
+
+	// Degradation level of the configuration producing the result, when analyzing
+	// adaptively under a time budget
+	Degradation DegradationLevel
+
+	// Points-to labels per allocation kind, if computed by LabelKindMetrics
+	LabelKinds map[LabelKind]LabelKindSizes
+	// Points-to set sizes per query type, if computed by QueryTypeMetrics
+	QueryTypes map[QueryType]QueryTypeSizes
+	// May-alias metrics, if computed by AliasMetrics
+	Aliasing *Aliasing
+	// Constraint system metrics, if collected by AnalyzeConstraints
+	Constraints *ConstraintMetrics
+	// Structural estimate of the result size, if computed by ResultSizeMetrics
+	ResultSize *ResultSize
+
+	// Points-to set sizes of queries and indirect queries, kept to merge metrics
+	// without the PTA results
+	sizes, indirectSizes []int
 }
 
 func (m PTAMetrics) String() string {
-	return fmt.Sprintf(`
+	str := fmt.Sprintf(`
 PTA METRICS
+- Analysis: %s
 - Duration: %f
+- Number of SSA functions: %d
+- Number of SSA instructions: %d
 - Number of PTA queries: %d
 - Number of indirect PTA queries: %d
 - P50 points-to set size: %d
@@ -37,8 +112,20 @@ PTA METRICS
 - P99 points-to set size: %d
 - Max points-to set size: %d
 - Most common points-to set size: %d
+- P50 indirect points-to set size: %d
+- P90 indirect points-to set size: %d
+- P99 indirect points-to set size: %d
+- Max indirect points-to set size: %d
+- Most common indirect points-to set size: %d
+- Number of synthetic entry calls: %d
+- Number of PTA warnings: %d
+- Retained result size (bytes): %d
+- Degradation level: %s
 `,
+		m.Analysis,
 		m.Duration.Seconds(),
+		m.Functions,
+		m.Instructions,
 		m.Queries,
 		m.IndirectQueries,
 		m.PointsToSetSizeP50,
@@ -46,7 +133,29 @@ PTA METRICS
 		m.PointsToSetSizeP99,
 		m.PointsToSetSizeMax,
 		m.PointsToSetSizeMode,
+		m.IndirectPointsToSetSizeP50,
+		m.IndirectPointsToSetSizeP90,
+		m.IndirectPointsToSetSizeP99,
+		m.IndirectPointsToSetSizeMax,
+		m.IndirectPointsToSetSizeMode,
+		m.SyntheticEntryCalls,
+		m.Warnings,
+		m.RetainedBytes,
+		m.Degradation,
 	)
+
+	str += m.warningsString()
+	str += m.labelKindsString() + m.queryTypesString()
+	if m.Aliasing != nil {
+		str += m.Aliasing.String()
+	}
+	if m.Constraints != nil {
+		str += m.Constraints.String()
+	}
+	if m.ResultSize != nil {
+		str += m.ResultSize.String()
+	}
+	return str
 }
 
 // AnalyzeWithTimeout runs the points-to analysis with the given configuration in the alloted time limit,
@@ -81,6 +190,11 @@ func Analyze(config *pointer.Config) PTAMetrics {
 	m = m.PointsToSetMetrics()
 	m.Queries = len(m.Payload.Queries)
 	m.IndirectQueries = len(m.Payload.IndirectQueries)
+	if len(config.Mains) > 0 {
+		prog := config.Mains[0].Prog
+		m.Functions, m.Instructions = programSize(prog)
+		m = m.WarningMetrics(prog.Fset)
+	}
 
 	return m
 }
@@ -116,23 +230,45 @@ func visitCallgraph(cg *callgraph.Graph, f func(n *callgraph.Node)) {
 	visit(cg.Root)
 }
 
-// PointsToSetMetrics computes metrics about the sizes of points-to sets.
+// PointsToSetMetrics computes metrics about the sizes of points-to sets,
+// separately for queries and indirect queries.
 func (m PTAMetrics) PointsToSetMetrics() PTAMetrics {
-	// Max size points-to set.
-	ptSizes := make([]int, 0, len(m.Payload.Queries))
-	for _, pt := range m.Payload.Queries {
-		ptSizes = append(ptSizes, len(pt.PointsTo().Labels()))
+	return m.withSizes(pointsToSetSizes(m.Payload.Queries), pointsToSetSizes(m.Payload.IndirectQueries))
+}
 
-		if m.PointsToSetSizeMax < len(pt.PointsTo().Labels()) {
-			m.PointsToSetSizeMax = len(pt.PointsTo().Labels())
-		}
+// errNoPointsToSets is produced by metrics requiring the points-to sets of the queries, when
+// the PTA result does not include them e.g., for the unification-based points-to analysis.
+var errNoPointsToSets = errors.New("the PTA result does not include the points-to sets of its queries")
+
+// queryResult returns the PTA result, if it includes the points-to sets of its queries.
+func (m PTAMetrics) queryResult() (*pointer.Result, error) {
+	if m.Payload == nil || m.Payload.Queries == nil {
+		return nil, errNoPointsToSets
 	}
+	return m.Payload, nil
+}
 
-	slices.Sort(ptSizes)
-	m.PointsToSetSizeMode = mode(ptSizes)
-	m.PointsToSetSizeP50 = p50(ptSizes)
-	m.PointsToSetSizeP90 = p90(ptSizes)
-	m.PointsToSetSizeP99 = p99(ptSizes)
+// withSizes summarizes the given points-to set sizes of queries and indirect queries,
+// and keeps them for merging.
+func (m PTAMetrics) withSizes(sizes, indirectSizes []int) PTAMetrics {
+	m.sizes, m.indirectSizes = sizes, indirectSizes
+	d := makeDistribution(sizes)
+	m.PointsToSetSizeP50, m.PointsToSetSizeP90, m.PointsToSetSizeP99 = d.P50, d.P90, d.P99
+	m.PointsToSetSizeMax, m.PointsToSetSizeMode = d.Max, d.Mode
 
+	d = makeDistribution(indirectSizes)
+	m.IndirectPointsToSetSizeP50, m.IndirectPointsToSetSizeP90, m.IndirectPointsToSetSizeP99 = d.P50, d.P90, d.P99
+	m.IndirectPointsToSetSizeMax, m.IndirectPointsToSetSizeMode = d.Max, d.Mode
 	return m
 }
+
+// pointsToSetSizes computes the sorted sizes of the points-to sets of the given queries.
+func pointsToSetSizes(queries map[ssa.Value]pointer.Pointer) []int {
+	ptSizes := make([]int, 0, len(queries))
+	for _, pt := range queries {
+		ptSizes = append(ptSizes, len(pt.PointsTo().Labels()))
+	}
+
+	slices.Sort(ptSizes)
+	return ptSizes
+}
@@ -0,0 +1,108 @@
+package stamets
+
+import (
+	"fmt"
+	"go/types"
+)
+
+// QueryType classifies PTA queries by the static type of the queried value.
+type QueryType string
+
+// Query types, by the underlying type of the queried value. Values of type reflect.Value,
+// which the points-to analysis treats as pointer-like, have a separate query type.
+const (
+	QueryPointer   QueryType = "pointer"
+	QueryChan      QueryType = "chan"
+	QueryMap       QueryType = "map"
+	QuerySlice     QueryType = "slice"
+	QueryFunc      QueryType = "func"
+	QueryInterface QueryType = "interface"
+	QueryReflect   QueryType = "reflect.Value"
+)
+
+// queryTypes lists all query types in the order they are reported.
+var queryTypes = []QueryType{
+	QueryPointer,
+	QueryChan,
+	QueryMap,
+	QuerySlice,
+	QueryFunc,
+	QueryInterface,
+	QueryReflect,
+}
+
+// queryType classifies a pointer-like type. The second result is false
+// for types which may not be queried.
+func queryType(t types.Type) (QueryType, bool) {
+	if named, ok := t.(*types.Named); ok {
+		if obj := named.Obj(); obj.Pkg() != nil && obj.Pkg().Path() == "reflect" && obj.Name() == "Value" {
+			return QueryReflect, true
+		}
+	}
+
+	switch t.Underlying().(type) {
+	case *types.Pointer:
+		return QueryPointer, true
+	case *types.Chan:
+		return QueryChan, true
+	case *types.Map:
+		return QueryMap, true
+	case *types.Slice:
+		return QuerySlice, true
+	case *types.Signature:
+		return QueryFunc, true
+	case *types.Interface:
+		return QueryInterface, true
+	}
+	return "", false
+}
+
+// QueryTypeSizes describes the points-to set sizes of the PTA queries of a single type.
+type QueryTypeSizes struct {
+	// Number of queries of the type
+	Queries int
+	// Distribution of the points-to set sizes of the queries
+	Distribution
+}
+
+// QueryTypeMetrics partitions the PTA queries by the static type of the queried values,
+// and computes the distribution of points-to set sizes for every type.
+func (m PTAMetrics) QueryTypeMetrics() PTAMetrics {
+	res, err := m.queryResult()
+	if err != nil {
+		m.err = err
+		return m
+	}
+
+	sizes := make(map[QueryType][]int)
+	for v, pt := range res.Queries {
+		if qt, ok := queryType(v.Type()); ok {
+			sizes[qt] = append(sizes[qt], len(pt.PointsTo().Labels()))
+		}
+	}
+
+	m.QueryTypes = make(map[QueryType]QueryTypeSizes, len(sizes))
+	for qt, ns := range sizes {
+		m.QueryTypes[qt] = QueryTypeSizes{
+			Queries:      len(ns),
+			Distribution: makeDistribution(ns),
+		}
+	}
+
+	return m
+}
+
+// queryTypesString renders the query type metrics, in the order of queryTypes.
+func (m PTAMetrics) queryTypesString() string {
+	if m.QueryTypes == nil {
+		return ""
+	}
+
+	str := "Points-to set sizes by query type:\n"
+	for _, qt := range queryTypes {
+		if s, ok := m.QueryTypes[qt]; ok {
+			str += fmt.Sprintf("\t- %s: %d queries, %s\n", qt, s.Queries, s.Distribution)
+		}
+	}
+	return str
+}
@@ -0,0 +1,59 @@
+package stamets
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/pointer"
+)
+
+func TestQueryType(t *testing.T) {
+	named := types.NewNamed(types.NewTypeName(0, nil, "C", nil), types.NewChan(types.SendRecv, types.Typ[types.Int]), nil)
+	value := types.NewNamed(types.NewTypeName(0, types.NewPackage("reflect", "reflect"), "Value", nil), types.NewStruct(nil, nil), nil)
+
+	for typ, expected := range map[types.Type]QueryType{
+		types.NewPointer(types.Typ[types.Int]): QueryPointer,
+		named:                                  QueryChan,
+		types.NewMap(types.Typ[types.String], types.Typ[types.Int]): QueryMap,
+		types.NewSlice(types.Typ[types.Int]):                        QuerySlice,
+		types.NewSignatureType(nil, nil, nil, nil, nil, false):      QueryFunc,
+		types.NewInterfaceType(nil, nil):                            QueryInterface,
+		value:                                                       QueryReflect,
+	} {
+		// Query types cover the pointer-like types of the points-to analysis.
+		require.True(t, pointer.CanPoint(typ))
+		qt, ok := queryType(typ)
+		require.True(t, ok)
+		require.Equal(t, expected, qt)
+	}
+
+	_, ok := queryType(types.Typ[types.Int])
+	require.False(t, ok)
+	_, ok = queryType(types.NewStruct(nil, nil))
+	require.False(t, ok)
+}
+
+func TestQueryTypeMetrics(t *testing.T) {
+	prog, _ := buildLibrary(t)
+
+	m := AnalyzeProgram(prog, ProgramOptions{
+		Scope:     ScopePackages,
+		Packages:  []string{"lib"},
+		Synthetic: true,
+	})
+	require.True(t, m.Ok())
+	require.Nil(t, m.QueryTypes)
+
+	m = m.QueryTypeMetrics()
+	total := 0
+	for _, s := range m.QueryTypes {
+		total += s.Queries
+	}
+	require.Equal(t, m.Queries, total)
+	for _, qt := range []QueryType{QueryPointer, QueryChan, QueryMap, QuerySlice} {
+		require.Contains(t, m.QueryTypes, qt)
+	}
+	require.Equal(t, 1, m.QueryTypes[QueryChan].Queries)
+	require.Contains(t, m.String(), "\t- chan: 1 queries")
+}
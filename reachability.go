@@ -0,0 +1,147 @@
+package stamets
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// syntheticPackage is the name under which functions without
+// a package e.g., wrappers, are reported.
+const syntheticPackage = "(synthetic)"
+
+// Reachability counts functions in relation to a call graph.
+type Reachability struct {
+	// Number of SSA functions
+	Functions int
+	// Number of functions with a node in the call graph
+	InGraph int
+	// Number of functions reachable from the root of the call graph
+	Reachable int
+	// Number of functions with a node in the call graph, which
+	// are not reachable from the root
+	Unreachable int
+}
+
+// DeadCodeRatio returns the ratio of SSA functions that are not
+// reachable from the root of the call graph.
+func (r Reachability) DeadCodeRatio() float64 {
+	if r.Functions == 0 {
+		return 0
+	}
+	return float64(r.Functions-r.Reachable) / float64(r.Functions)
+}
+
+func (r Reachability) add(o Reachability) Reachability {
+	return Reachability{
+		Functions:   r.Functions + o.Functions,
+		InGraph:     r.InGraph + o.InGraph,
+		Reachable:   r.Reachable + o.Reachable,
+		Unreachable: r.Unreachable + o.Unreachable,
+	}
+}
+
+// ReachabilityMetrics relates the functions of an SSA program to
+// a call graph e.g., as produced by PTA/CHA/RTA, to quantify how much
+// of the program the call graph considers live.
+type ReachabilityMetrics struct {
+	BaseMetrics[*callgraph.Graph]
+
+	// Totals over all packages
+	Reachability
+
+	// Per-package breakdown, indexed by package path
+	Packages map[string]Reachability
+}
+
+func (m ReachabilityMetrics) String() string {
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, `
+REACHABILITY METRICS
+- Number of SSA functions: %d
+- Functions in call graph: %d
+- Functions reachable from root: %d
+- Unreachable call graph functions: %d
+- Dead code ratio: %f
+Per-package reachability:
+`,
+		m.Functions,
+		m.InGraph,
+		m.Reachable,
+		m.Unreachable,
+		m.DeadCodeRatio(),
+	)
+
+	pkgs := maps.Keys(m.Packages)
+	slices.Sort(pkgs)
+	for _, pkg := range pkgs {
+		r := m.Packages[pkg]
+		fmt.Fprintf(buf, "\t- %s: functions %d, in graph %d, reachable %d, unreachable %d, dead code ratio %f\n",
+			pkg, r.Functions, r.InGraph, r.Reachable, r.Unreachable, r.DeadCodeRatio())
+	}
+
+	return buf.String()
+}
+
+// GetReachabilityMetrics computes reachability metrics for the functions in
+// an SSA program, with respect to the given call graph.
+func GetReachabilityMetrics(prog *ssa.Program, cg *callgraph.Graph) ReachabilityMetrics {
+	return reachabilityMetrics(ssautil.AllFunctions(prog), cg)
+}
+
+func reachabilityMetrics(funcs map[*ssa.Function]bool, cg *callgraph.Graph) ReachabilityMetrics {
+	m := ReachabilityMetrics{
+		BaseMetrics: BaseMetrics[*callgraph.Graph]{
+			Payload: cg,
+		},
+		Packages: make(map[string]Reachability),
+	}
+
+	reachable := make(map[*ssa.Function]struct{})
+	visitCallgraph(cg, func(n *callgraph.Node) {
+		if n.Func != nil {
+			reachable[n.Func] = struct{}{}
+		}
+	})
+
+	// Functions may be created lazily during the analysis that produced the call graph
+	// e.g., wrappers, in which case they are missing from the set of SSA functions.
+	all := make(map[*ssa.Function]struct{}, len(funcs))
+	for fn := range funcs {
+		all[fn] = struct{}{}
+	}
+	if cg != nil {
+		for fn := range cg.Nodes {
+			if fn != nil {
+				all[fn] = struct{}{}
+			}
+		}
+	}
+
+	for fn := range all {
+		r := Reachability{Functions: 1}
+
+		if cg != nil && cg.Nodes[fn] != nil {
+			r.InGraph = 1
+			if _, ok := reachable[fn]; ok {
+				r.Reachable = 1
+			} else {
+				r.Unreachable = 1
+			}
+		}
+
+		pkg := packagePath(functionPackage(fn))
+		if pkg == "" {
+			pkg = syntheticPackage
+		}
+		m.Packages[pkg] = m.Packages[pkg].add(r)
+		m.Reachability = m.Reachability.add(r)
+	}
+
+	return m
+}
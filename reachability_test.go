@@ -0,0 +1,43 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestReachabilityMetrics(t *testing.T) {
+	cg, nodes := makeCallgraph(t)
+
+	// Unreachable node calling into the reachable part of the call graph.
+	unreachable := cg.CreateNode(&ssa.Function{})
+	callgraph.AddEdge(unreachable, &ssa.Call{}, nodes[3])
+
+	funcs := make(map[*ssa.Function]bool)
+	for fn := range cg.Nodes {
+		funcs[fn] = true
+	}
+	// Functions absent from the call graph.
+	funcs[&ssa.Function{}] = true
+	funcs[&ssa.Function{}] = true
+
+	m := reachabilityMetrics(funcs, cg)
+
+	require.Equal(t, 10, m.Functions)
+	require.Equal(t, 8, m.InGraph)
+	require.Equal(t, 7, m.Reachable)
+	require.Equal(t, 1, m.Unreachable)
+	require.Equal(t, 0.3, m.DeadCodeRatio())
+	require.Equal(t, map[string]Reachability{
+		syntheticPackage: m.Reachability,
+	}, m.Packages)
+
+	m = reachabilityMetrics(funcs, nil)
+	require.Equal(t, 10, m.Functions)
+	require.Zero(t, m.InGraph)
+	require.Equal(t, 1.0, m.DeadCodeRatio())
+
+	require.Zero(t, Reachability{}.DeadCodeRatio())
+}
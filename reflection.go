@@ -0,0 +1,113 @@
+package stamets
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/tools/go/pointer"
+)
+
+// ReflectionComparison compares the points-to analysis of the same program
+// with and without sound handling of reflection. The payload is the compared
+// analysis configuration.
+type ReflectionComparison struct {
+	BaseMetrics[*pointer.Config]
+
+	Without PTAMetrics
+	With    PTAMetrics
+
+	// Bytes allocated by the analysis, without and with reflection
+	WithoutAllocated uint64
+	WithAllocated    uint64
+
+	// Call graph metrics, without and with reflection
+	WithoutCallGraph CallGraphMetrics
+	WithCallGraph    CallGraphMetrics
+}
+
+func (c ReflectionComparison) String() string {
+	if !c.Ok() {
+		_, without := c.Without.Unpack()
+		_, with := c.With.Unpack()
+		return fmt.Sprintf(`
+REFLECTION COMPARISON
+- Error without reflection: %v
+- Error with reflection: %v
+`, without, with)
+	}
+
+	row := func(name string, without, with int) string {
+		return fmt.Sprintf("- %s: %d -> %d (%+d)\n", name, without, with, with-without)
+	}
+
+	str := fmt.Sprintf(`
+REFLECTION COMPARISON
+- Duration: %f -> %f (%+f)
+`,
+		c.Without.Duration.Seconds(),
+		c.With.Duration.Seconds(),
+		(c.With.Duration - c.Without.Duration).Seconds(),
+	)
+	str += row("Allocated bytes", int(c.WithoutAllocated), int(c.WithAllocated))
+	str += row("Number of PTA queries", c.Without.Queries, c.With.Queries)
+	str += row("P50 points-to set size", c.Without.PointsToSetSizeP50, c.With.PointsToSetSizeP50)
+	str += row("P90 points-to set size", c.Without.PointsToSetSizeP90, c.With.PointsToSetSizeP90)
+	str += row("P99 points-to set size", c.Without.PointsToSetSizeP99, c.With.PointsToSetSizeP99)
+	str += row("Max points-to set size", c.Without.PointsToSetSizeMax, c.With.PointsToSetSizeMax)
+	str += row("Most common points-to set size", c.Without.PointsToSetSizeMode, c.With.PointsToSetSizeMode)
+	str += row("Number of indirect PTA queries", c.Without.IndirectQueries, c.With.IndirectQueries)
+	str += row("P50 indirect points-to set size", c.Without.IndirectPointsToSetSizeP50, c.With.IndirectPointsToSetSizeP50)
+	str += row("P90 indirect points-to set size", c.Without.IndirectPointsToSetSizeP90, c.With.IndirectPointsToSetSizeP90)
+	str += row("P99 indirect points-to set size", c.Without.IndirectPointsToSetSizeP99, c.With.IndirectPointsToSetSizeP99)
+	str += row("Max indirect points-to set size", c.Without.IndirectPointsToSetSizeMax, c.With.IndirectPointsToSetSizeMax)
+	str += row("Most common indirect points-to set size", c.Without.IndirectPointsToSetSizeMode, c.With.IndirectPointsToSetSizeMode)
+	str += row("Number of call graph functions", c.WithoutCallGraph.NumberOfFunctions(), c.WithCallGraph.NumberOfFunctions())
+	str += row("Number of call graph edges", c.WithoutCallGraph.NumberOfEdges(), c.WithCallGraph.NumberOfEdges())
+	str += row("Number of PTA warnings", c.Without.Warnings, c.With.Warnings)
+
+	return str
+}
+
+// CompareReflection runs the points-to analysis with the given configuration twice, without
+// and with sound handling of reflection, collecting metrics for both, regardless of the
+// Reflection setting of the configuration. The call graph is always constructed.
+func CompareReflection(config *pointer.Config) ReflectionComparison {
+	analyze := func(reflection bool) (PTAMetrics, uint64, CallGraphMetrics) {
+		cfg := *config
+		cfg.Reflection = reflection
+		cfg.BuildCallGraph = true
+
+		var m PTAMetrics
+		allocated := allocatedBy(func() {
+			m = Analyze(&cfg)
+		})
+		if !m.Ok() {
+			return m, allocated, CallGraphMetrics{}
+		}
+		return m, allocated, GetCallGraphMetrics(m.Payload.CallGraph)
+	}
+
+	start := time.Now()
+	c := ReflectionComparison{
+		BaseMetrics: BaseMetrics[*pointer.Config]{
+			Payload: config,
+		},
+	}
+	c.Without, c.WithoutAllocated, c.WithoutCallGraph = analyze(false)
+	c.With, c.WithAllocated, c.WithCallGraph = analyze(true)
+	c.Duration = time.Since(start)
+
+	_, without := c.Without.Unpack()
+	_, with := c.With.Unpack()
+	c.err = errors.Join(without, with)
+	return c
+}
+
+// CompareReflectionWithTimeout runs the points-to analysis without and with reflection
+// like CompareReflection, in the alloted time limit.
+func CompareReflectionWithTimeout(t time.Duration, config *pointer.Config) (ReflectionComparison, bool) {
+	return TaskWithTimeout(t, func() ReflectionComparison {
+		return CompareReflection(config)
+	})
+}
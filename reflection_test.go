@@ -0,0 +1,122 @@
+package stamets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// loadProgram loads the packages matching the patterns in the given directory,
+// and builds the SSA program of all packages.
+func loadProgram(t *testing.T, dir string, patterns ...string) ([]*packages.Package, *ssa.Program) {
+	m := PackagesLoad(&packages.Config{Mode: packages.LoadAllSyntax, Dir: dir}, patterns...)
+	require.True(t, m.Ok())
+	require.Zero(t, packages.PrintErrors(m.Payload))
+
+	prog, _ := ssautil.AllPackages(m.Payload, ssa.InstantiateGenerics)
+	prog.Build()
+	return m.Payload, prog
+}
+
+func TestCompareReflection(t *testing.T) {
+	// The reflection model requires the reflect package, which is not available
+	// to programs built from source. The analysis remains sound without it.
+	prog := buildProgram(t, map[string]string{
+		"main": `package main
+
+type I interface{ M() *int }
+type T struct{ f *int }
+
+func (t *T) M() *int { return t.f }
+
+func main() {
+	x := &T{f: new(int)}
+	var i I = x
+	println(i.M())
+}
+`,
+	}, "main")
+
+	main := prog.ImportedPackage("main")
+	config := &pointer.Config{Mains: []*ssa.Package{main}}
+	config.AddQuery(main.Func("main").Blocks[0].Instrs[0].(*ssa.Alloc))
+
+	c := CompareReflection(config)
+	require.True(t, c.Ok())
+	require.False(t, config.Reflection)
+	require.Nil(t, config.Log)
+	require.NotZero(t, c.WithoutAllocated)
+	require.NotZero(t, c.WithAllocated)
+	require.NotZero(t, c.WithoutCallGraph.NumberOfEdges())
+	require.Equal(t, 1, c.Without.Queries)
+	require.Equal(t, 1, c.With.Queries)
+	require.Contains(t, c.String(), "- Number of call graph edges: ")
+
+	c = CompareReflection(&pointer.Config{})
+	require.False(t, c.Ok())
+	require.Contains(t, c.String(), "- Error without reflection: ")
+}
+
+func TestCompareReflectionCall(t *testing.T) {
+	_, prog := loadProgram(t, writeFiles(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.20\n",
+		"main.go": `package main
+
+import "reflect"
+
+type T struct{ f *int }
+
+func main() {
+	x := &T{f: new(int)}
+	get := func(t *T) *int { return t.f }
+	out := reflect.ValueOf(get).Call([]reflect.Value{reflect.ValueOf(x)})
+	p := out[0].Interface().(*int)
+	println(p)
+}
+`,
+	}), ".")
+
+	config, _, err := programConfig(prog, ProgramOptions{
+		Scope:    ScopePackages,
+		Packages: []string{"example.com/m"},
+	})
+	require.NoError(t, err)
+	require.NotZero(t, len(config.IndirectQueries))
+
+	c := CompareReflection(config)
+	require.True(t, c.Ok())
+	require.Equal(t, c.Without.IndirectQueries, c.With.IndirectQueries)
+	require.NotZero(t, c.With.IndirectPointsToSetSizeMax)
+
+	// The function called through reflection is only reachable with reflection.
+	main := prog.ImportedPackage("example.com/m").Func("main")
+	calls := func(m PTAMetrics) bool {
+		n := m.Payload.CallGraph.Nodes[main.AnonFuncs[0]]
+		return n != nil && len(n.In) > 0
+	}
+	require.False(t, calls(c.Without))
+	require.True(t, calls(c.With))
+	require.Greater(t, c.WithCallGraph.NumberOfEdges(), c.WithoutCallGraph.NumberOfEdges())
+
+	// The result of the reflective call only points to the field with reflection.
+	var p ssa.Value
+	for _, b := range main.Blocks {
+		for _, instr := range b.Instrs {
+			if ta, ok := instr.(*ssa.TypeAssert); ok {
+				p = ta
+			}
+		}
+	}
+	require.NotNil(t, p)
+	require.Empty(t, c.Without.Payload.Queries[p].PointsTo().Labels())
+	require.NotEmpty(t, c.With.Payload.Queries[p].PointsTo().Labels())
+
+	str := c.String()
+	require.Contains(t, str, "- Number of indirect PTA queries: ")
+	require.Contains(t, str, "- P90 indirect points-to set size: ")
+	require.Contains(t, str, "- Most common indirect points-to set size: ")
+}
@@ -29,3 +29,52 @@ func AllPackagesWithTimeout(t time.Duration, pkgs []*packages.Package, mode ssa.
 		return AllPackages(pkgs, mode)
 	})
 }
+
+// functionPackage returns the package a function belongs to. Functions
+// without a package e.g., instantiations and anonymous functions, are
+// attributed to the package of their origin or their enclosing function.
+// Shared synthetic functions e.g., wrappers, have no package.
+func functionPackage(fn *ssa.Function) *ssa.Package {
+	switch {
+	case fn == nil:
+		return nil
+	case fn.Pkg != nil:
+		return fn.Pkg
+	case fn.Origin() != nil:
+		return functionPackage(fn.Origin())
+	case fn.Parent() != nil:
+		return functionPackage(fn.Parent())
+	}
+	return nil
+}
+
+// packagePath returns the import path of an SSA package, or the empty string
+// if the package is nil.
+func packagePath(pkg *ssa.Package) string {
+	if pkg == nil || pkg.Pkg == nil {
+		return ""
+	}
+	return pkg.Pkg.Path()
+}
+
+// valuePackage returns the package a value belongs to, or nil if it cannot be determined.
+func valuePackage(v ssa.Value) *ssa.Package {
+	switch v := v.(type) {
+	case *ssa.Function:
+		return functionPackage(v)
+	case *ssa.Global:
+		return v.Pkg
+	}
+	return functionPackage(v.Parent())
+}
+
+// programSize returns the number of functions and instructions in an SSA program.
+func programSize(prog *ssa.Program) (functions, instructions int) {
+	for fn := range ssautil.AllFunctions(prog) {
+		functions++
+		for _, b := range fn.Blocks {
+			instructions += len(b.Instrs)
+		}
+	}
+	return
+}
@@ -0,0 +1,341 @@
+package stamets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"hash/fnv"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/ssa"
+)
+
+const (
+	// syntheticPath is the package path prefix of synthetic main packages.
+	syntheticPath = "stamets.synthetic"
+	// syntheticFile is the file name of synthetic main packages.
+	syntheticFile = "stamets_synthetic.go"
+)
+
+// isSynthetic checks whether a package is a synthetic main package.
+func isSynthetic(pkg *ssa.Package) bool {
+	return strings.HasPrefix(pkg.Pkg.Path(), syntheticPath+"/")
+}
+
+// syntheticMu serializes the creation of synthetic main packages.
+var syntheticMu sync.Mutex
+
+// syntheticCall is a call to an entry point in a synthetic main package.
+type syntheticCall struct {
+	// Import paths of the packages used by the call
+	imports []string
+	// Source code of the call, as a statement
+	stmt string
+}
+
+// syntheticWriter renders calls to functions with fresh arguments, naming
+// the packages of types and functions by aliases.
+type syntheticWriter struct {
+	aliases map[*types.Package]string
+	paths   []string
+	used    []string
+}
+
+func (w *syntheticWriter) qualifier(pkg *types.Package) string {
+	if _, ok := w.aliases[pkg]; !ok {
+		w.aliases[pkg] = fmt.Sprintf("p%d", len(w.paths))
+		w.paths = append(w.paths, pkg.Path())
+	}
+	if !slices.Contains(w.used, pkg.Path()) {
+		w.used = append(w.used, pkg.Path())
+	}
+	return w.aliases[pkg]
+}
+
+// typeExpr renders a type expression, if the type can be named outside its package.
+func (w *syntheticWriter) typeExpr(t types.Type) (string, bool) {
+	expressible := true
+	var check func(types.Type)
+	check = func(t types.Type) {
+		switch t := t.(type) {
+		case *types.Named:
+			if !t.Obj().Exported() && t.Obj().Pkg() != nil {
+				expressible = false
+			}
+			for i := 0; i < t.TypeArgs().Len(); i++ {
+				check(t.TypeArgs().At(i))
+			}
+		case *types.TypeParam:
+			expressible = false
+		case *types.Pointer:
+			check(t.Elem())
+		case *types.Slice:
+			check(t.Elem())
+		case *types.Array:
+			check(t.Elem())
+		case *types.Map:
+			check(t.Key())
+			check(t.Elem())
+		case *types.Chan:
+			check(t.Elem())
+		case *types.Signature:
+			for i := 0; i < t.Params().Len(); i++ {
+				check(t.Params().At(i).Type())
+			}
+			for i := 0; i < t.Results().Len(); i++ {
+				check(t.Results().At(i).Type())
+			}
+		case *types.Struct:
+			for i := 0; i < t.NumFields(); i++ {
+				if !t.Field(i).Exported() {
+					expressible = false
+				}
+				check(t.Field(i).Type())
+			}
+		}
+	}
+	check(t)
+	if !expressible {
+		return "", false
+	}
+	return types.TypeString(t, w.qualifier), true
+}
+
+// freshArg renders an expression producing a fresh value of the given type. Pointers
+// point to fresh allocations, and maps, channels and slices are freshly made.
+func (w *syntheticWriter) freshArg(t types.Type) (string, bool) {
+	expr, ok := w.typeExpr(t)
+	if !ok {
+		return "", false
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		if elem, ok := w.typeExpr(u.Elem()); ok {
+			return fmt.Sprintf("(%s)(new(%s))", expr, elem), true
+		}
+	case *types.Map, *types.Chan:
+		return fmt.Sprintf("make(%s)", expr), true
+	case *types.Slice:
+		return fmt.Sprintf("make(%s, 1)", expr), true
+	}
+	return fmt.Sprintf("*new(%s)", expr), true
+}
+
+// entryPoint is an exported function, or an exported method of an exported type.
+type entryPoint struct {
+	fn *types.Func
+	// Receiver type for methods, nil for functions
+	recv *types.Named
+}
+
+// call renders a call to an entry point with fresh arguments. The receiver of
+// methods is a fresh allocation of the receiver type.
+func (w *syntheticWriter) call(e entryPoint) (syntheticCall, bool) {
+	w.used = nil
+	sig := e.fn.Type().(*types.Signature)
+	if sig.TypeParams().Len() > 0 {
+		return syntheticCall{}, false
+	}
+
+	var callee string
+	if e.recv != nil {
+		expr, ok := w.typeExpr(e.recv)
+		if !ok {
+			return syntheticCall{}, false
+		}
+		callee = fmt.Sprintf("new(%s).%s", expr, e.fn.Name())
+	} else {
+		callee = w.qualifier(e.fn.Pkg()) + "." + e.fn.Name()
+	}
+
+	args := make([]string, 0, sig.Params().Len())
+	for i := 0; i < sig.Params().Len(); i++ {
+		arg, ok := w.freshArg(sig.Params().At(i).Type())
+		if !ok {
+			return syntheticCall{}, false
+		}
+		if sig.Variadic() && i == sig.Params().Len()-1 {
+			arg += "..."
+		}
+		args = append(args, arg)
+	}
+
+	return syntheticCall{
+		imports: w.used,
+		stmt:    fmt.Sprintf("%s(%s)", callee, strings.Join(args, ", ")),
+	}, true
+}
+
+// entryPoints collects the exported functions, and exported methods of exported types, of a package.
+func entryPoints(pkg *ssa.Package) (entries []entryPoint) {
+	names := make([]string, 0, len(pkg.Members))
+	for name := range pkg.Members {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		switch mem := pkg.Members[name].(type) {
+		case *ssa.Function:
+			if obj, ok := mem.Object().(*types.Func); ok && obj.Exported() {
+				entries = append(entries, entryPoint{fn: obj})
+			}
+		case *ssa.Type:
+			named, ok := mem.Type().(*types.Named)
+			if !ok || !mem.Object().Exported() || types.IsInterface(named) || named.TypeParams().Len() > 0 {
+				continue
+			}
+			mset := types.NewMethodSet(types.NewPointer(named))
+			for i := 0; i < mset.Len(); i++ {
+				if obj, ok := mset.At(i).Obj().(*types.Func); ok && obj.Exported() {
+					entries = append(entries, entryPoint{fn: obj, recv: named})
+				}
+			}
+		}
+	}
+	return entries
+}
+
+// syntheticSource renders the source code of a synthetic main package making the given calls.
+func syntheticSource(w *syntheticWriter, calls []syntheticCall) []byte {
+	imports := make(map[string]struct{})
+	for _, c := range calls {
+		for _, path := range c.imports {
+			imports[path] = struct{}{}
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "package main\n\nimport (\n")
+	for i, path := range w.paths {
+		if _, ok := imports[path]; ok {
+			fmt.Fprintf(buf, "\tp%d %q\n", i, path)
+		}
+	}
+	fmt.Fprintf(buf, ")\n\nfunc main() {\n")
+	for _, c := range calls {
+		fmt.Fprintf(buf, "\t%s\n", c.stmt)
+	}
+	fmt.Fprintf(buf, "}\n")
+	return buf.Bytes()
+}
+
+// synthesizeMain creates a synthetic main package in the program, whose main function calls
+// the given entry points with fresh arguments. Calls that are ill-typed e.g., due to types
+// that are inaccessible outside their package, are discarded. It returns the built package,
+// and the number of calls to entry points.
+//
+// The package is added to the program, which is therefore mutated, and must not be used
+// concurrently e.g., by another analysis, while the package is created. Packages are identified
+// by the calls to entry points, such that synthesizing the same calls again returns the package
+// created previously.
+func synthesizeMain(prog *ssa.Program, entries []entryPoint) (*ssa.Package, int, error) {
+	w := &syntheticWriter{aliases: make(map[*types.Package]string)}
+	var calls []syntheticCall
+	for _, e := range entries {
+		if c, ok := w.call(e); ok {
+			calls = append(calls, c)
+		}
+	}
+
+	h := fnv.New64a()
+	h.Write(syntheticSource(w, calls))
+	path := fmt.Sprintf("%s/%x", syntheticPath, h.Sum64())
+
+	syntheticMu.Lock()
+	defer syntheticMu.Unlock()
+	for _, pkg := range prog.AllPackages() {
+		if pkg.Pkg.Path() == path {
+			return pkg, syntheticCalls(pkg), nil
+		}
+	}
+
+	// Imports are resolved to the type-checked packages of the program.
+	imports := make(map[string]*types.Package)
+	for _, pkg := range prog.AllPackages() {
+		imports[pkg.Pkg.Path()] = pkg.Pkg
+	}
+
+	for {
+		src := syntheticSource(w, calls)
+		f, err := parser.ParseFile(prog.Fset, syntheticFile, src, 0)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		// Every call is on its own line, after the imports.
+		firstCall := prog.Fset.Position(f.Decls[len(f.Decls)-1].(*ast.FuncDecl).Body.Lbrace).Line + 1
+		illTyped := make(map[int]struct{})
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Scopes:     make(map[ast.Node]*types.Scope),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Instances:  make(map[*ast.Ident]types.Instance),
+		}
+		tc := &types.Config{
+			Importer: importerFunc(func(path string) (*types.Package, error) {
+				if pkg, ok := imports[path]; ok {
+					return pkg, nil
+				}
+				return nil, fmt.Errorf("package %q is not in the program", path)
+			}),
+			Error: func(err error) {
+				if terr, ok := err.(types.Error); ok {
+					illTyped[terr.Fset.Position(terr.Pos).Line-firstCall] = struct{}{}
+				}
+			},
+		}
+		pkg, _ := tc.Check(path, prog.Fset, []*ast.File{f}, info)
+
+		if len(illTyped) == 0 {
+			if len(calls) == 0 {
+				return nil, 0, errors.New("no entry points found")
+			}
+			ssaPkg := prog.CreatePackage(pkg, []*ast.File{f}, info, false)
+			ssaPkg.Build()
+			return ssaPkg, len(calls), nil
+		}
+
+		wellTyped := make([]syntheticCall, 0, len(calls))
+		for i, c := range calls {
+			if _, ok := illTyped[i]; !ok {
+				wellTyped = append(wellTyped, c)
+			}
+		}
+		if len(wellTyped) == len(calls) {
+			// Errors are not attributable to calls.
+			return nil, 0, errors.New("failed to type-check synthetic main package")
+		}
+		calls = wellTyped
+	}
+}
+
+// syntheticCalls counts the calls to entry points of a synthetic main package. Every call
+// to an entry point is a separate statement, whose arguments are allocated without calls.
+func syntheticCalls(pkg *ssa.Package) int {
+	calls := 0
+	for _, b := range pkg.Func("main").Blocks {
+		for _, instr := range b.Instrs {
+			if _, ok := instr.(*ssa.Call); ok {
+				calls++
+			}
+		}
+	}
+	return calls
+}
+
+// importerFunc implements types.Importer with a function.
+type importerFunc func(path string) (*types.Package, error)
+
+func (f importerFunc) Import(path string) (*types.Package, error) {
+	return f(path)
+}
@@ -0,0 +1,105 @@
+package stamets
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+const librarySource = `package lib
+
+type T struct{ next *T }
+
+func (t *T) Next() *T { return t.next }
+func (t T) Value() T  { return t }
+func (t *T) unexported() {}
+
+type hidden struct{}
+
+func New(next *T) *T                 { return &T{next: next} }
+func Hidden(h hidden)                {}
+func Generic[X any](x X) X           { return x }
+func Variadic(ts ...*T) []*T         { return ts }
+func Collections(m map[string]*T, c chan *T, s []*T) {}
+func unexported()                    {}
+`
+
+// buildLibrary builds an SSA program from a library package without imports.
+func buildLibrary(t *testing.T) (*ssa.Program, *ssa.Package) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "lib.go", librarySource, 0)
+	require.NoError(t, err)
+
+	pkg, _, err := ssautil.BuildPackage(&types.Config{}, fset, types.NewPackage("lib", "lib"), []*ast.File{f}, ssa.InstantiateGenerics)
+	require.NoError(t, err)
+	return pkg.Prog, pkg
+}
+
+func TestSynthesizeMain(t *testing.T) {
+	prog, lib := buildLibrary(t)
+
+	entries := entryPoints(lib)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, e.fn.Name())
+	}
+	require.Equal(t, []string{"Collections", "Generic", "Hidden", "New", "Next", "Value", "Variadic"}, names)
+
+	main, calls, err := synthesizeMain(prog, entries)
+	require.NoError(t, err)
+	require.True(t, isSynthetic(main))
+	// Generic functions and functions with inaccessible types are not called.
+	require.Equal(t, 5, calls)
+	require.NotNil(t, main.Func("main"))
+
+	// Synthetic main packages are not discovered as main packages.
+	require.Empty(t, programMains(prog, false))
+	for _, fn := range rootFunctions(prog) {
+		require.False(t, isSynthetic(fn.Pkg))
+	}
+
+	// Synthesizing the same calls reuses the package.
+	packages := len(prog.AllPackages())
+	again, calls, err := synthesizeMain(prog, entries)
+	require.NoError(t, err)
+	require.Same(t, main, again)
+	require.Equal(t, 5, calls)
+	require.Len(t, prog.AllPackages(), packages)
+
+	other, calls, err := synthesizeMain(prog, entries[:1])
+	require.NoError(t, err)
+	require.NotSame(t, main, other)
+	require.Equal(t, 1, calls)
+
+	res, err := pointer.Analyze(&pointer.Config{
+		Mains:          []*ssa.Package{main},
+		BuildCallGraph: true,
+	})
+	require.NoError(t, err)
+	for _, name := range []string{"New", "Collections", "Variadic"} {
+		require.NotNil(t, res.CallGraph.Nodes[lib.Func(name)])
+	}
+}
+
+func TestAnalyzeProgramSynthetic(t *testing.T) {
+	prog, _ := buildLibrary(t)
+
+	m := AnalyzeProgram(prog, ProgramOptions{
+		Scope:     ScopePackages,
+		Packages:  []string{"lib"},
+		Synthetic: true,
+	})
+	require.True(t, m.Ok())
+	require.Equal(t, 5, m.SyntheticEntryCalls)
+	require.NotZero(t, m.Queries)
+
+	m = AnalyzeProgram(prog, ProgramOptions{})
+	require.False(t, m.Ok())
+}
@@ -0,0 +1,179 @@
+package stamets
+
+import (
+	"errors"
+	"fmt"
+	"go/types"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// TestPackageMetrics aggregates the metrics of analyzing the test binary of a package.
+type TestPackageMetrics struct {
+	// Path of the package under test
+	Package string
+
+	PTA       PTAMetrics
+	CallGraph CallGraphMetrics
+}
+
+// TestMetrics aggregates the metrics of analyzing the test binaries of a program.
+type TestMetrics struct {
+	BaseMetrics[[]TestPackageMetrics]
+}
+
+func (m TestMetrics) String() string {
+	str := fmt.Sprintf(`
+TEST METRICS
+- Duration: %f
+- Number of test packages: %d
+`,
+		m.Duration.Seconds(),
+		len(m.Payload),
+	)
+
+	for _, t := range m.Payload {
+		str += fmt.Sprintf("- Test package %s:\n", t.Package)
+		if _, err := t.PTA.Unpack(); err != nil {
+			str += fmt.Sprintf("\t- Error: %v\n", err)
+			continue
+		}
+		str += t.PTA.String()
+		if t.CallGraph.Payload != nil {
+			str += t.CallGraph.String()
+		}
+	}
+
+	return str
+}
+
+// AnalyzeTests runs the points-to analysis on every test binary of an SSA program, collecting PTA
+// and call graph metrics per package under test. Test binaries are analyzed from the test main
+// packages, as generated when loading packages with tests. If the program has no test main packages,
+// the analysis of every test binary starts instead from a synthetic main package, calling every
+// test function of the package under test.
+//
+// Values are queried according to the scope of the options. The call graph is always constructed.
+func AnalyzeTests(prog *ssa.Program, opts ProgramOptions) TestMetrics {
+	start := time.Now()
+	err := opts.validate()
+	if prog == nil {
+		err = errNoProgram
+	}
+	if err != nil {
+		return TestMetrics{
+			BaseMetrics: BaseMetrics[[]TestPackageMetrics]{
+				err: err,
+			},
+		}
+	}
+	opts.BuildCallGraph = true
+
+	analyze := func(path string, main *ssa.Package) TestPackageMetrics {
+		t := TestPackageMetrics{
+			Package: path,
+			PTA:     Analyze(pointerConfig(prog, []*ssa.Package{main}, opts)),
+		}
+		if t.PTA.Ok() {
+			t.CallGraph = GetCallGraphMetrics(t.PTA.Payload.CallGraph)
+		}
+		return t
+	}
+
+	var ms []TestPackageMetrics
+	if mains := programMains(prog, true); len(mains) > 0 {
+		for _, main := range mains {
+			ms = append(ms, analyze(strings.TrimSuffix(main.Pkg.Path(), ".test"), main))
+		}
+	} else {
+		tests := testFunctions(prog)
+		paths := maps.Keys(tests)
+		slices.Sort(paths)
+
+		for _, path := range paths {
+			main, calls, err := synthesizeMain(prog, tests[path])
+			if err != nil {
+				ms = append(ms, TestPackageMetrics{
+					Package: path,
+					PTA: PTAMetrics{
+						BaseMetrics: BaseMetrics[*pointer.Result]{
+							err: err,
+						},
+					},
+				})
+				continue
+			}
+
+			t := analyze(path, main)
+			t.PTA.SyntheticEntryCalls = calls
+			ms = append(ms, t)
+		}
+	}
+
+	m := TestMetrics{
+		BaseMetrics: BaseMetrics[[]TestPackageMetrics]{
+			Payload:  ms,
+			Duration: time.Since(start),
+		},
+	}
+	if len(ms) == 0 {
+		m.err = errors.New("no tests found")
+	}
+	return m
+}
+
+// AnalyzeTestsWithTimeout runs the points-to analysis on every test binary of an SSA program
+// like AnalyzeTests, in the alloted time limit.
+func AnalyzeTestsWithTimeout(t time.Duration, prog *ssa.Program, opts ProgramOptions) (TestMetrics, bool) {
+	return TaskWithTimeout(t, func() TestMetrics {
+		return AnalyzeTests(prog, opts)
+	})
+}
+
+// isTestFunction checks whether a function is a test function i.e., it is named
+// TestXxx, and takes a single *testing.T argument.
+func isTestFunction(fn *types.Func) bool {
+	name := strings.TrimPrefix(fn.Name(), "Test")
+	if name == fn.Name() {
+		return false
+	}
+	if r, _ := utf8.DecodeRuneInString(name); unicode.IsLower(r) {
+		return false
+	}
+
+	sig := fn.Type().(*types.Signature)
+	if sig.Recv() != nil || sig.Params().Len() != 1 || sig.Results().Len() != 0 {
+		return false
+	}
+	ptr, ok := sig.Params().At(0).Type().(*types.Pointer)
+	if !ok {
+		return false
+	}
+	named, ok := ptr.Elem().(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == "testing" && named.Obj().Name() == "T"
+}
+
+// testFunctions collects the test functions of a program, indexed by the path of the package
+// under test. Test functions in external test packages are attributed to the package under test.
+func testFunctions(prog *ssa.Program) map[string][]entryPoint {
+	tests := make(map[string][]entryPoint)
+	for _, pkg := range prog.AllPackages() {
+		if isSynthetic(pkg) {
+			continue
+		}
+		path := strings.TrimSuffix(pkg.Pkg.Path(), "_test")
+		for _, e := range entryPoints(pkg) {
+			if e.recv == nil && isTestFunction(e.fn) {
+				tests[path] = append(tests[path], e)
+			}
+		}
+	}
+	return tests
+}
@@ -0,0 +1,139 @@
+package stamets
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+)
+
+// buildProgram builds an SSA program from the given package sources, in dependency order.
+// Packages are named after the last element of their path.
+func buildProgram(t *testing.T, srcs map[string]string, order ...string) *ssa.Program {
+	fset := token.NewFileSet()
+	prog := ssa.NewProgram(fset, ssa.InstantiateGenerics)
+
+	pkgs := make(map[string]*types.Package)
+	for _, path := range order {
+		f, err := parser.ParseFile(fset, path+".go", srcs[path], 0)
+		require.NoError(t, err)
+
+		info := &types.Info{
+			Types:      make(map[ast.Expr]types.TypeAndValue),
+			Defs:       make(map[*ast.Ident]types.Object),
+			Uses:       make(map[*ast.Ident]types.Object),
+			Implicits:  make(map[ast.Node]types.Object),
+			Scopes:     make(map[ast.Node]*types.Scope),
+			Selections: make(map[*ast.SelectorExpr]*types.Selection),
+			Instances:  make(map[*ast.Ident]types.Instance),
+		}
+		tc := &types.Config{Importer: importerFunc(func(path string) (*types.Package, error) {
+			return pkgs[path], nil
+		})}
+		pkg, err := tc.Check(path, fset, []*ast.File{f}, info)
+		require.NoError(t, err)
+		pkgs[path] = pkg
+
+		prog.CreatePackage(pkg, []*ast.File{f}, info, true)
+	}
+	prog.Build()
+
+	return prog
+}
+
+func TestAnalyzeTestsSynthetic(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"testing": `package testing
+
+type T struct{ name string }
+`,
+		"lib": `package lib
+
+import "testing"
+
+type S struct{ next *S }
+
+func TestA(t *testing.T) { _ = &S{} }
+func TestB(t *testing.T) { TestA(t) }
+func Testing(t *testing.T) {}
+func TestC(t *testing.T) int { return 0 }
+func TestD(s *S) {}
+`,
+	}, "testing", "lib")
+
+	tests := testFunctions(prog)
+	require.Len(t, tests, 1)
+	names := []string{}
+	for _, e := range tests["lib"] {
+		names = append(names, e.fn.Name())
+	}
+	require.Equal(t, []string{"TestA", "TestB"}, names)
+
+	m := AnalyzeTests(prog, ProgramOptions{Scope: ScopePackages, Packages: []string{"lib"}})
+	require.True(t, m.Ok())
+	require.Len(t, m.Payload, 1)
+
+	lib := m.Payload[0]
+	require.Equal(t, "lib", lib.Package)
+	require.True(t, lib.PTA.Ok())
+	require.Equal(t, 2, lib.PTA.SyntheticEntryCalls)
+	require.NotNil(t, lib.CallGraph.Payload)
+	require.NotNil(t, lib.CallGraph.Payload.Nodes[prog.ImportedPackage("lib").Func("TestA")])
+
+	m = AnalyzeTests(prog, ProgramOptions{Scope: ScopePackages})
+	require.False(t, m.Ok())
+
+	_, err := AnalyzeTests(nil, ProgramOptions{}).Unpack()
+	require.ErrorIs(t, err, errNoProgram)
+}
+
+func TestAnalyzeTestsLoaded(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"go.mod":          "module example.com/m\n\ngo 1.20\n",
+		"lib/lib.go":      "package lib\n\ntype T struct{ next *T }\n\nfunc New() *T { return &T{next: &T{}} }\n",
+		"lib/lib_test.go": "package lib\n\nimport \"testing\"\n\nfunc TestNew(t *testing.T) {\n\tif New().next == nil {\n\t\tt.Fail()\n\t}\n}\n",
+		"other/other.go":  "package other\n",
+	})
+	m := PackagesLoad(&packages.Config{Mode: packages.LoadAllSyntax, Dir: dir, Tests: true}, "./...")
+	require.True(t, m.Ok())
+	require.Zero(t, packages.PrintErrors(m.Payload))
+	prog, _ := ssautil.AllPackages(m.Payload, ssa.InstantiateGenerics)
+	prog.Build()
+
+	// Only the generated test main package of the package with tests is analyzed.
+	tm := AnalyzeTests(prog, ProgramOptions{Scope: ScopeMainModule, Module: "example.com/m"})
+	require.True(t, tm.Ok())
+	require.Len(t, tm.Payload, 1)
+
+	lib := tm.Payload[0]
+	require.Equal(t, "example.com/m/lib", lib.Package)
+	require.True(t, lib.PTA.Ok())
+	require.Zero(t, lib.PTA.SyntheticEntryCalls)
+	require.NotZero(t, lib.PTA.Queries)
+	require.True(t, lib.CallGraph.Ok())
+	require.NotZero(t, lib.CallGraph.Functions)
+
+	var test, constructor *callgraph.Node
+	for fn, n := range lib.PTA.Payload.CallGraph.Nodes {
+		if fn != nil && fn.Pkg != nil && fn.Pkg.Pkg.Path() == "example.com/m/lib" {
+			switch fn.Name() {
+			case "TestNew":
+				test = n
+			case "New":
+				constructor = n
+			}
+		}
+	}
+	require.NotNil(t, test)
+	require.NotNil(t, constructor)
+	require.NotEmpty(t, test.In)
+	require.Contains(t, callees(test), constructor.Func)
+	require.Contains(t, tm.String(), "- Test package example.com/m/lib:")
+}
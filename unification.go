@@ -0,0 +1,727 @@
+package stamets
+
+import (
+	"errors"
+	"go/token"
+	"go/types"
+	"time"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+// unode is a node of the unification-based points-to analysis. Nodes form equivalence classes
+// via union-find. A class of values points to a single class of objects, and a class of objects
+// contains a single class of values, regardless of fields.
+type unode struct {
+	parent *unode
+	rank   int
+
+	// Class pointed to by the members of the class
+	ref *unode
+	// Allocation sites of the objects in the class
+	labels []ssa.Value
+	// Signature shared by the functions in the class of objects
+	sig *usignature
+}
+
+// usignature unifies the parameters and results of the functions in a class of objects.
+type usignature struct {
+	params  []*unode
+	results []*unode
+}
+
+func (n *unode) find() *unode {
+	for n.parent != nil {
+		if n.parent.parent != nil {
+			n.parent = n.parent.parent
+		}
+		n = n.parent
+	}
+	return n
+}
+
+// UnificationPointer is the points-to set of a query of the unification-based points-to analysis.
+type UnificationPointer struct {
+	n *unode
+}
+
+// PointsTo returns the allocation sites of the objects a query may point to. Allocation sites are
+// the instructions allocating objects, or globals and functions.
+func (p UnificationPointer) PointsTo() []ssa.Value {
+	if p.n == nil {
+		return nil
+	}
+	if ref := p.n.find().ref; ref != nil {
+		return ref.find().labels
+	}
+	return nil
+}
+
+// MayAlias checks whether two queries may point to the same object. Under unification, points-to
+// sets are either identical or disjoint.
+func (p UnificationPointer) MayAlias(q UnificationPointer) bool {
+	if p.n == nil || q.n == nil {
+		return false
+	}
+	pref, qref := p.n.find().ref, q.n.find().ref
+	return pref != nil && qref != nil && pref.find() == qref.find() && len(pref.find().labels) > 0
+}
+
+// UnificationResult is the result of the unification-based points-to analysis.
+type UnificationResult struct {
+	// Call graph, rooted at a node without function calling the entry points of the main packages
+	CallGraph *callgraph.Graph
+	// Points-to sets of the queried values in reachable functions, and of globals
+	Queries map[ssa.Value]UnificationPointer
+	// Points-to sets of the values pointed to by the indirectly queried values
+	IndirectQueries map[ssa.Value]UnificationPointer
+}
+
+// unifier is the state of the unification-based points-to analysis.
+type unifier struct {
+	prog *ssa.Program
+	// runtime.SetFinalizer, if the runtime package is in the program
+	setFinalizer *ssa.Function
+
+	values   map[ssa.Value]*unode
+	elements map[uelement]*unode
+	objects  map[ssa.Value]*unode
+	returns  map[*ssa.Function][]*unode
+
+	reachable map[*ssa.Function]struct{}
+	queue     []*ssa.Function
+
+	// Class of the values passed to panic, and returned by recover, in any function
+	panics *unode
+
+	// Dynamic call sites and their callees, in the order callees were discovered
+	dynamic []dynamicSite
+	edges   []uedge
+}
+
+// uelement is an element of a tuple value.
+type uelement struct {
+	tuple ssa.Value
+	index int
+}
+
+// dynamicSite is a call site through a function value or an interface. The class of the called
+// values is the class of the function value or interface, or of the finalizers of runtime.SetFinalizer.
+type dynamicSite struct {
+	caller  *ssa.Function
+	site    ssa.CallInstruction
+	value   *unode
+	callees map[*ssa.Function]struct{}
+}
+
+// uedge is a call graph edge discovered by the unification-based points-to analysis.
+type uedge struct {
+	caller *ssa.Function
+	site   ssa.CallInstruction
+	callee *ssa.Function
+}
+
+// join unifies two classes, and then, recursively, the classes they point to and their signatures.
+func (u *unifier) join(x, y *unode) {
+	pending := [][2]*unode{{x, y}}
+	for len(pending) > 0 {
+		x, y := pending[len(pending)-1][0].find(), pending[len(pending)-1][1].find()
+		pending = pending[:len(pending)-1]
+		if x == y {
+			continue
+		}
+
+		if x.rank < y.rank {
+			x, y = y, x
+		}
+		y.parent = x
+		if x.rank == y.rank {
+			x.rank++
+		}
+		if len(x.labels) < len(y.labels) {
+			x.labels, y.labels = y.labels, x.labels
+		}
+		x.labels = append(x.labels, y.labels...)
+		y.labels = nil
+
+		switch {
+		case x.ref == nil:
+			x.ref = y.ref
+		case y.ref != nil:
+			pending = append(pending, [2]*unode{x.ref, y.ref})
+		}
+
+		switch {
+		case x.sig == nil:
+			x.sig = y.sig
+		case y.sig != nil:
+			for i := 0; i < len(x.sig.params) && i < len(y.sig.params); i++ {
+				pending = append(pending, [2]*unode{x.sig.params[i], y.sig.params[i]})
+			}
+			for i := 0; i < len(x.sig.results) && i < len(y.sig.results); i++ {
+				pending = append(pending, [2]*unode{x.sig.results[i], y.sig.results[i]})
+			}
+			// Only unrelated functions, unified due to field insensitivity, differ in arity.
+			if len(y.sig.params) > len(x.sig.params) {
+				x.sig.params = y.sig.params
+			}
+			if len(y.sig.results) > len(x.sig.results) {
+				x.sig.results = y.sig.results
+			}
+		}
+		y.sig = nil
+	}
+}
+
+// ref returns the class pointed to by the members of a class, creating it if needed.
+func (u *unifier) ref(n *unode) *unode {
+	n = n.find()
+	if n.ref == nil {
+		n.ref = &unode{}
+	}
+	return n.ref.find()
+}
+
+// value returns the class of an SSA value. Globals and functions point to themselves, and functions
+// referenced as values are analyzed, like by the inclusion-based analysis.
+func (u *unifier) value(v ssa.Value) *unode {
+	if n, ok := u.values[v]; ok {
+		return n
+	}
+	n := &unode{}
+	u.values[v] = n
+
+	switch v := v.(type) {
+	case *ssa.Global:
+		u.join(u.ref(n), u.object(v))
+	case *ssa.Function:
+		u.join(u.object(v), &unode{sig: u.signature(v)})
+		u.join(u.ref(n), u.object(v))
+		u.reach(v)
+	}
+	return n
+}
+
+// element returns the class of an element of a tuple value, or the class of the value
+// if it is not a tuple. Unlike other aggregates, the elements of tuples are distinguished.
+func (u *unifier) element(v ssa.Value, i int) *unode {
+	if _, ok := v.Type().(*types.Tuple); !ok {
+		return u.value(v)
+	}
+	k := uelement{v, i}
+	if n, ok := u.elements[k]; ok {
+		return n
+	}
+	n := &unode{}
+	u.elements[k] = n
+	return n
+}
+
+// object returns the class of the object allocated at an allocation site.
+func (u *unifier) object(site ssa.Value) *unode {
+	if n, ok := u.objects[site]; ok {
+		return n
+	}
+	n := &unode{labels: []ssa.Value{site}}
+	u.objects[site] = n
+	return n
+}
+
+// signature constructs the signature of a function from its parameters and results.
+func (u *unifier) signature(fn *ssa.Function) *usignature {
+	sig := &usignature{results: u.results(fn)}
+	for _, p := range fn.Params {
+		sig.params = append(sig.params, u.value(p))
+	}
+	return sig
+}
+
+// results returns the classes of the results of a function.
+func (u *unifier) results(fn *ssa.Function) []*unode {
+	if ns, ok := u.returns[fn]; ok {
+		return ns
+	}
+	ns := freshNodes(fn.Signature.Results().Len())
+	u.returns[fn] = ns
+	return ns
+}
+
+// freshNodes creates n fresh classes.
+func freshNodes(n int) []*unode {
+	ns := make([]*unode, n)
+	for i := range ns {
+		ns[i] = &unode{}
+	}
+	return ns
+}
+
+// callResults unifies the results of a call site with the given classes of results.
+func (u *unifier) callResults(site ssa.CallInstruction, results []*unode) {
+	v := site.Value()
+	if v == nil {
+		return
+	}
+	n := 1
+	if t, ok := v.Type().(*types.Tuple); ok {
+		n = t.Len()
+	}
+	for i := 0; i < n && i < len(results); i++ {
+		u.join(u.element(v, i), results[i])
+	}
+}
+
+// alloc adds an allocation site to the points-to set of the value it allocates.
+func (u *unifier) alloc(v ssa.Value) {
+	u.join(u.ref(u.value(v)), u.object(v))
+}
+
+// copy unifies the points-to sets of two values.
+func (u *unifier) copy(dst, src ssa.Value) {
+	u.join(u.value(dst), u.value(src))
+}
+
+// load unifies a class of values with the contents of the objects pointed to by a pointer.
+func (u *unifier) load(dst *unode, ptr ssa.Value) {
+	u.join(dst, u.ref(u.ref(u.value(ptr))))
+}
+
+// store unifies the contents of the objects pointed to by a pointer with the points-to set of a value.
+func (u *unifier) store(ptr, src ssa.Value) {
+	u.join(u.ref(u.ref(u.value(ptr))), u.value(src))
+}
+
+// reach marks a function as reachable, scheduling the generation of its constraints.
+func (u *unifier) reach(fn *ssa.Function) {
+	if _, ok := u.reachable[fn]; ok {
+		return
+	}
+	u.reachable[fn] = struct{}{}
+	u.queue = append(u.queue, fn)
+}
+
+// call unifies the arguments and results of a call site with the parameters and results of a callee.
+// The receiver of interface method calls is given separately.
+func (u *unifier) call(caller *ssa.Function, site ssa.CallInstruction, callee *ssa.Function, recv *unode) {
+	u.reach(callee)
+	u.edges = append(u.edges, uedge{caller, site, callee})
+
+	params := callee.Params
+	if recv != nil && len(params) > 0 {
+		u.join(u.value(params[0]), recv)
+		params = params[1:]
+	}
+	for i, arg := range site.Common().Args {
+		if i < len(params) {
+			u.join(u.value(params[i]), u.value(arg))
+		}
+	}
+	u.callResults(site, u.results(callee))
+}
+
+// generate generates the constraints of the instructions of a function.
+func (u *unifier) generate(fn *ssa.Function) {
+	for _, b := range fn.Blocks {
+		for _, instr := range b.Instrs {
+			u.instruction(fn, instr)
+		}
+	}
+}
+
+// instruction generates the constraints of an instruction.
+func (u *unifier) instruction(fn *ssa.Function, instr ssa.Instruction) {
+	switch instr := instr.(type) {
+	case *ssa.Alloc, *ssa.MakeMap, *ssa.MakeChan, *ssa.MakeSlice:
+		u.alloc(instr.(ssa.Value))
+	case *ssa.MakeInterface:
+		u.alloc(instr)
+		u.store(instr, instr.X)
+	case *ssa.MakeClosure:
+		closure := instr.Fn.(*ssa.Function)
+		u.join(u.object(instr), &unode{sig: u.signature(closure)})
+		u.alloc(instr)
+		for i, binding := range instr.Bindings {
+			u.copy(closure.FreeVars[i], binding)
+		}
+	case *ssa.Convert:
+		// Conversions from strings to slices allocate.
+		if _, ok := instr.Type().Underlying().(*types.Slice); ok {
+			u.alloc(instr)
+		} else {
+			u.copy(instr, instr.X)
+		}
+	case *ssa.ChangeType:
+		u.copy(instr, instr.X)
+	case *ssa.ChangeInterface:
+		u.copy(instr, instr.X)
+	case *ssa.SliceToArrayPointer:
+		u.copy(instr, instr.X)
+	case *ssa.MultiConvert:
+		u.copy(instr, instr.X)
+	case *ssa.Slice:
+		u.copy(instr, instr.X)
+	case *ssa.FieldAddr:
+		u.copy(instr, instr.X)
+	case *ssa.IndexAddr:
+		u.copy(instr, instr.X)
+	case *ssa.Field:
+		u.copy(instr, instr.X)
+	case *ssa.Index:
+		u.copy(instr, instr.X)
+	case *ssa.Extract:
+		u.join(u.value(instr), u.element(instr.Tuple, instr.Index))
+	case *ssa.Phi:
+		for _, e := range instr.Edges {
+			u.copy(instr, e)
+		}
+	case *ssa.UnOp:
+		if instr.Op == token.MUL || instr.Op == token.ARROW {
+			u.load(u.element(instr, 0), instr.X)
+		}
+	case *ssa.Store:
+		u.store(instr.Addr, instr.Val)
+	case *ssa.Send:
+		u.store(instr.Chan, instr.X)
+	case *ssa.MapUpdate:
+		u.store(instr.Map, instr.Key)
+		u.store(instr.Map, instr.Value)
+	case *ssa.Lookup:
+		if _, ok := instr.X.Type().Underlying().(*types.Map); ok {
+			u.load(u.element(instr, 0), instr.X)
+		}
+	case *ssa.Next:
+		// Keys and values of maps are not distinguished.
+		if !instr.IsString {
+			u.load(u.element(instr, 1), instr.Iter)
+			u.load(u.element(instr, 2), instr.Iter)
+		}
+	case *ssa.Range:
+		u.copy(instr, instr.X)
+	case *ssa.TypeAssert:
+		if types.IsInterface(instr.AssertedType) {
+			u.join(u.element(instr, 0), u.value(instr.X))
+		} else {
+			u.load(u.element(instr, 0), instr.X)
+		}
+	case *ssa.Select:
+		// Received values follow the index of the chosen state and whether it received a value.
+		recv := 2
+		for _, st := range instr.States {
+			if st.Dir == types.SendOnly {
+				u.store(st.Chan, st.Send)
+			} else {
+				u.load(u.element(instr, recv), st.Chan)
+				recv++
+			}
+		}
+	case *ssa.Panic:
+		u.join(u.panics, u.value(instr.X))
+	case *ssa.Return:
+		for i, r := range instr.Results {
+			u.join(u.results(fn)[i], u.value(r))
+		}
+	case ssa.CallInstruction:
+		u.callInstruction(fn, instr)
+	}
+}
+
+// callInstruction generates the constraints of a call, go or defer instruction.
+func (u *unifier) callInstruction(fn *ssa.Function, site ssa.CallInstruction) {
+	common := site.Common()
+	// Functions passed as arguments are analyzed even if no callee is known yet,
+	// or the callee has no body.
+	for _, arg := range common.Args {
+		u.value(arg)
+	}
+
+	switch {
+	case common.IsInvoke():
+		u.dynamic = append(u.dynamic, dynamicSite{fn, site, u.value(common.Value), make(map[*ssa.Function]struct{})})
+	case u.setFinalizer != nil && common.StaticCallee() == u.setFinalizer:
+		u.finalizer(fn, site)
+	case common.StaticCallee() != nil:
+		u.call(fn, site, common.StaticCallee(), nil)
+	default:
+		if b, ok := common.Value.(*ssa.Builtin); ok {
+			u.builtin(site, b)
+			return
+		}
+
+		// Calls through function values share the signature of the functions they may call.
+		u.join(u.ref(u.value(common.Value)), &unode{sig: &usignature{
+			params:  freshNodes(len(common.Args)),
+			results: freshNodes(common.Signature().Results().Len()),
+		}})
+		sig := u.ref(u.value(common.Value)).sig
+		for i, arg := range common.Args {
+			if i < len(sig.params) {
+				u.join(sig.params[i], u.value(arg))
+			}
+		}
+		u.callResults(site, sig.results)
+		u.dynamic = append(u.dynamic, dynamicSite{fn, site, u.value(common.Value), make(map[*ssa.Function]struct{})})
+	}
+}
+
+// finalizer generates the constraints of a call to runtime.SetFinalizer. Like the inclusion-based
+// analysis, the call site calls the finalizer with the object directly, instead of the runtime.
+func (u *unifier) finalizer(fn *ssa.Function, site ssa.CallInstruction) {
+	args := site.Common().Args
+	// The finalizer and its argument are boxed in interfaces.
+	finalizer := u.ref(u.ref(u.value(args[1])))
+	u.join(u.ref(finalizer), &unode{sig: &usignature{
+		params:  []*unode{u.ref(u.ref(u.value(args[0])))},
+		results: freshNodes(0),
+	}})
+	u.dynamic = append(u.dynamic, dynamicSite{fn, site, finalizer, make(map[*ssa.Function]struct{})})
+}
+
+// builtin generates the constraints of calls to the builtins manipulating pointers, including
+// the builtins inserted by go/ssa.
+func (u *unifier) builtin(site ssa.CallInstruction, b *ssa.Builtin) {
+	args := site.Common().Args
+	switch b.Name() {
+	case "append":
+		v := site.Value()
+		u.alloc(v)
+		u.copy(v, args[0])
+		u.join(u.ref(u.ref(u.value(v))), u.ref(u.ref(u.value(args[1]))))
+	case "copy":
+		u.join(u.ref(u.ref(u.value(args[0]))), u.ref(u.ref(u.value(args[1]))))
+	case "recover":
+		if v := site.Value(); v != nil {
+			u.join(u.value(v), u.panics)
+		}
+	case "ssa:wrapnilchk":
+		// Wrappers of value methods check their pointer receiver before dereferencing it.
+		if v := site.Value(); v != nil {
+			u.copy(v, args[0])
+		}
+	}
+}
+
+// ucallee is a callee of a dynamic call site. Callees of interface method calls have
+// the class of their receiver.
+type ucallee struct {
+	fn   *ssa.Function
+	recv *unode
+}
+
+// resolution identifies the callees of dynamic call sites through the same class of objects,
+// calling the same interface method, if any.
+type resolution struct {
+	objects *unode
+	method  *types.Func
+}
+
+// callees finds the functions in a class of objects, or the implementations of an interface method
+// by the objects in the class. Function values are unified with unrelated objects due to field
+// insensitivity, which are skipped.
+func (u *unifier) callees(r resolution) []ucallee {
+	var cs []ucallee
+	seen := make(map[*ssa.Function]struct{})
+	for _, l := range r.objects.labels {
+		var c ucallee
+		switch l := l.(type) {
+		case *ssa.Function:
+			c.fn = l
+		case *ssa.MakeClosure:
+			c.fn = l.Fn.(*ssa.Function)
+		case *ssa.MakeInterface:
+			if r.method == nil {
+				continue
+			}
+			sel := u.prog.MethodSets.MethodSet(l.X.Type()).Lookup(r.method.Pkg(), r.method.Name())
+			if sel == nil {
+				continue
+			}
+			c.fn, c.recv = u.prog.MethodValue(sel), u.ref(u.object(l))
+		}
+		if c.fn == nil || (r.method != nil) != (c.recv != nil) {
+			continue
+		}
+		if _, ok := seen[c.fn]; !ok {
+			seen[c.fn] = struct{}{}
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// resolve connects a dynamic call site to the callees it may call, as currently known. Callees are
+// shared by the call sites through the same class of objects, and are memoized per pass over the
+// dynamic call sites. It reports whether new callees were found.
+func (u *unifier) resolve(d dynamicSite, memo map[resolution][]ucallee) bool {
+	r := resolution{u.ref(d.value), d.site.Common().Method}
+	cs, ok := memo[r]
+	if !ok {
+		cs = u.callees(r)
+		memo[r] = cs
+	}
+
+	found := false
+	for _, c := range cs {
+		if _, ok := d.callees[c.fn]; ok {
+			continue
+		}
+		d.callees[c.fn] = struct{}{}
+		found = true
+
+		if c.recv != nil {
+			u.call(d.caller, d.site, c.fn, c.recv)
+		} else {
+			// Arguments and results are already unified via the signature.
+			u.reach(c.fn)
+			u.edges = append(u.edges, uedge{d.caller, d.site, c.fn})
+		}
+	}
+	return found
+}
+
+// Unify runs a unification-based (Steensgaard-style) points-to analysis from the main packages of
+// the configuration, answering its queries and constructing the call graph. The analysis is
+// field-insensitive and context-insensitive, and unifies the points-to sets of values flowing into
+// each other, trading precision for near-linear running time. Functions are analyzed once they
+// are reachable from the main packages, and dynamic calls are resolved until a fixpoint. Like the
+// inclusion-based analysis, functions referenced as values and methods of runtime types are analyzed
+// even if they are never called.
+//
+// Reflection is not modelled, and functions without bodies e.g., intrinsics, have no effects.
+// Like by the inclusion-based analysis, calls to runtime.SetFinalizer call the finalizer directly,
+// and values passed to panic may be returned by any call to recover.
+func Unify(config *pointer.Config) (*UnificationResult, error) {
+	if len(config.Mains) == 0 {
+		return nil, errors.New("no main packages")
+	}
+
+	u := &unifier{
+		prog:      config.Mains[0].Prog,
+		values:    make(map[ssa.Value]*unode),
+		elements:  make(map[uelement]*unode),
+		objects:   make(map[ssa.Value]*unode),
+		returns:   make(map[*ssa.Function][]*unode),
+		reachable: make(map[*ssa.Function]struct{}),
+		panics:    &unode{},
+	}
+	if runtime := u.prog.ImportedPackage("runtime"); runtime != nil {
+		u.setFinalizer = runtime.Func("SetFinalizer")
+	}
+
+	var roots []*ssa.Function
+	for _, main := range config.Mains {
+		for _, name := range []string{"init", "main"} {
+			if fn := main.Func(name); fn != nil {
+				roots = append(roots, fn)
+				u.reach(fn)
+			}
+		}
+	}
+
+	for _, T := range u.prog.RuntimeTypes() {
+		if types.IsInterface(T) {
+			continue
+		}
+		mset := u.prog.MethodSets.MethodSet(T)
+		for i := 0; i < mset.Len(); i++ {
+			u.value(u.prog.MethodValue(mset.At(i)))
+		}
+	}
+
+	for {
+		for len(u.queue) > 0 {
+			fn := u.queue[0]
+			u.queue = u.queue[1:]
+			u.generate(fn)
+		}
+
+		// A pass without new callees unifies no classes, such that its memoized callees are exact.
+		found, memo := false, make(map[resolution][]ucallee)
+		for _, d := range u.dynamic {
+			if u.resolve(d, memo) {
+				found = true
+			}
+		}
+		if !found && len(u.queue) == 0 {
+			break
+		}
+	}
+
+	res := &UnificationResult{
+		CallGraph:       callgraph.New(nil),
+		Queries:         make(map[ssa.Value]UnificationPointer),
+		IndirectQueries: make(map[ssa.Value]UnificationPointer),
+	}
+	for _, fn := range roots {
+		callgraph.AddEdge(res.CallGraph.Root, nil, res.CallGraph.CreateNode(fn))
+	}
+	for _, e := range u.edges {
+		callgraph.AddEdge(res.CallGraph.CreateNode(e.caller), e.site, res.CallGraph.CreateNode(e.callee))
+	}
+
+	reachable := func(v ssa.Value) bool {
+		if fn := v.Parent(); fn != nil {
+			_, ok := u.reachable[fn]
+			return ok
+		}
+		return true
+	}
+	for v := range config.Queries {
+		if reachable(v) {
+			res.Queries[v] = UnificationPointer{u.value(v)}
+		}
+	}
+	for v := range config.IndirectQueries {
+		if reachable(v) {
+			res.IndirectQueries[v] = UnificationPointer{u.ref(u.ref(u.value(v)))}
+		}
+	}
+
+	return res, nil
+}
+
+// AnalyzeUnification runs the unification-based points-to analysis with the given configuration
+// via Unify, collecting PTA metrics like Analyze. The PTA result in the payload only includes the
+// call graph, which is always constructed.
+func AnalyzeUnification(config *pointer.Config) PTAMetrics {
+	start := time.Now()
+
+	res, err := Unify(config)
+	if err != nil {
+		return PTAMetrics{
+			BaseMetrics: BaseMetrics[*pointer.Result]{
+				err: err,
+			},
+		}
+	}
+
+	m := PTAMetrics{
+		BaseMetrics: BaseMetrics[*pointer.Result]{
+			Duration: time.Since(start),
+			Payload:  &pointer.Result{CallGraph: res.CallGraph},
+		},
+		Analysis: AnalysisUnification,
+	}
+
+	m.Functions, m.Instructions = programSize(config.Mains[0].Prog)
+	m.Queries = len(res.Queries)
+	m.IndirectQueries = len(res.IndirectQueries)
+	return m.withSizes(unificationSizes(res.Queries), unificationSizes(res.IndirectQueries))
+}
+
+// AnalyzeUnificationWithTimeout runs the unification-based points-to analysis like AnalyzeUnification,
+// in the alloted time limit.
+func AnalyzeUnificationWithTimeout(t time.Duration, config *pointer.Config) (PTAMetrics, bool) {
+	return TaskWithTimeout(t, func() PTAMetrics {
+		return AnalyzeUnification(config)
+	})
+}
+
+// unificationSizes computes the sizes of the points-to sets of the given queries.
+func unificationSizes(queries map[ssa.Value]UnificationPointer) []int {
+	sizes := make([]int, 0, len(queries))
+	for _, p := range queries {
+		sizes = append(sizes, len(p.PointsTo()))
+	}
+	return sizes
+}
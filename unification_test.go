@@ -0,0 +1,220 @@
+package stamets
+
+import (
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/pointer"
+	"golang.org/x/tools/go/ssa"
+)
+
+func TestUnify(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"main": `package main
+
+type T struct{ f *int }
+
+type I interface{ M() *int }
+
+func (t *T) M() *int { return t.f }
+
+type U struct{}
+
+func (U) M() *int { return nil }
+
+func id(t *T) *T { return t }
+
+func get(t *T) *T { return t }
+
+func apply(f func(*T) *T, t *T) *T { return f(t) }
+
+func main() {
+	a, b, c := &T{f: new(int)}, &T{}, &T{}
+	id(a)
+	id(b)
+	var i I = c
+	i.M()
+	var j I = U{}
+	j.M()
+	apply(get, c)
+}
+`,
+	}, "main")
+	main := prog.ImportedPackage("main")
+
+	var ts []ssa.Value
+	for _, b := range main.Func("main").Blocks {
+		for _, instr := range b.Instrs {
+			if a, ok := instr.(*ssa.Alloc); ok && a.Type().String() == "*main.T" {
+				ts = append(ts, a)
+			}
+		}
+	}
+	require.Len(t, ts, 3)
+
+	config := pointerConfig(prog, []*ssa.Package{main}, ProgramOptions{})
+	res, err := Unify(config)
+	require.NoError(t, err)
+
+	// Passing a and b to the same function unifies their points-to sets.
+	a, b, c := res.Queries[ts[0]], res.Queries[ts[1]], res.Queries[ts[2]]
+	require.ElementsMatch(t, []ssa.Value{ts[0], ts[1]}, a.PointsTo())
+	require.ElementsMatch(t, []ssa.Value{ts[0], ts[1]}, b.PointsTo())
+	require.Equal(t, []ssa.Value{ts[2]}, c.PointsTo())
+	require.True(t, a.MayAlias(b))
+	require.False(t, a.MayAlias(c))
+
+	callees := func(fn *ssa.Function) (names []string) {
+		for _, e := range res.CallGraph.Nodes[fn].Out {
+			names = append(names, e.Callee.Func.String())
+		}
+		return names
+	}
+	require.ElementsMatch(t, []string{"main.id", "main.id", "(*main.T).M", "(main.U).M", "main.apply"}, callees(main.Func("main")))
+	require.Equal(t, []string{"main.get"}, callees(main.Func("apply")))
+	require.Len(t, res.CallGraph.Root.Out, 2)
+
+	// Inclusion-based analysis keeps the points-to sets of a and b separate.
+	ptr, err := pointer.Analyze(config)
+	require.NoError(t, err)
+	require.Len(t, ptr.Queries[ts[0]].PointsTo().Labels(), 1)
+
+	m := AnalyzeUnification(config)
+	require.True(t, m.Ok())
+	require.Equal(t, AnalysisUnification, m.Analysis)
+	require.Contains(t, m.String(), "- Analysis: unification")
+	require.Equal(t, len(res.Queries), m.Queries)
+	require.Equal(t, len(res.IndirectQueries), m.IndirectQueries)
+	require.Equal(t, 2, m.PointsToSetSizeMax)
+	require.NotZero(t, m.Functions)
+	require.NotNil(t, m.Payload.CallGraph)
+
+	// The PTA result does not include the points-to sets of the queries.
+	require.False(t, m.LabelKindMetrics().Ok())
+	require.False(t, m.AliasMetrics().Ok())
+	require.False(t, m.QueryTypeMetrics().Ok())
+	require.False(t, m.ResultSizeMetrics().Ok())
+	_, err = m.ImpreciseQueries(prog, 1, 1)
+	require.Error(t, err)
+
+	m = AnalyzeProgram(prog, ProgramOptions{Unification: true, BuildCallGraph: true})
+	require.True(t, m.Ok())
+	require.NotEmpty(t, callgraph.CalleesOf(m.Payload.CallGraph.Nodes[main.Func("apply")]))
+
+	require.False(t, AnalyzeProgram(prog, ProgramOptions{Unification: true, Reflection: true}).Ok())
+	require.False(t, AnalyzeProgram(prog, ProgramOptions{Unification: true, Constraints: true}).Ok())
+
+	_, err = Unify(&pointer.Config{})
+	require.Error(t, err)
+}
+
+func TestUnifyValueMethodWrapper(t *testing.T) {
+	prog := buildProgram(t, map[string]string{
+		"main": `package main
+
+type T struct{ f *int }
+
+type I interface{ M() *int }
+
+func (t T) M() *int { return t.f }
+
+func main() {
+	var i I = &T{f: new(int)}
+	println(i.M())
+}
+`,
+	}, "main")
+	main := prog.ImportedPackage("main")
+
+	var x, r ssa.Value
+	for _, b := range main.Func("main").Blocks {
+		for _, instr := range b.Instrs {
+			switch instr := instr.(type) {
+			case *ssa.Alloc:
+				if instr.Type().String() == "*int" {
+					x = instr
+				}
+			case *ssa.Call:
+				if instr.Call.IsInvoke() {
+					r = instr
+				}
+			}
+		}
+	}
+	require.NotNil(t, x)
+	require.NotNil(t, r)
+
+	// The call reaches T.M through the (*T).M wrapper, which checks its receiver via ssa:wrapnilchk.
+	config := pointerConfig(prog, []*ssa.Package{main}, ProgramOptions{})
+	res, err := Unify(config)
+	require.NoError(t, err)
+	require.Contains(t, res.Queries[r].PointsTo(), x)
+}
+
+func TestUnifyCallGraphSoundness(t *testing.T) {
+	_, prog := loadProgram(t, writeFiles(t, map[string]string{
+		"go.mod": "module example.com/m\n\ngo 1.20\n",
+		"main.go": `package main
+
+import (
+	"errors"
+	"runtime"
+)
+
+type I interface{ Do(func()) }
+
+type T struct{ name string }
+
+func g() { h() }
+func h() {}
+
+func fin(t *T) { println(t.name) }
+
+type E struct{}
+
+func (E) Error() string { return "" }
+
+func try(f func()) (err error) {
+	defer func() {
+		if e, ok := recover().(error); ok {
+			err = errors.New(e.Error())
+		}
+	}()
+	f()
+	return nil
+}
+
+func main() {
+	// Functions passed to calls without a known callee, or to functions without bodies.
+	var s I
+	if s != nil {
+		s.Do(g)
+	}
+	runtime.SetFinalizer(&T{}, fin)
+
+	// Values passed to panic are returned by recover.
+	try(func() { panic(E{}) })
+}
+`,
+	}), ".")
+
+	main := prog.ImportedPackage("example.com/m")
+	config := &pointer.Config{Mains: []*ssa.Package{main}, BuildCallGraph: true}
+	pta := Analyze(config)
+	require.True(t, pta.Ok())
+	unif := AnalyzeUnification(config)
+	require.True(t, unif.Ok())
+
+	// The unification call graph is a superset of the PTA call graph.
+	c := CompareCallGraphs(pta.Payload.CallGraph, unif.Payload.CallGraph)
+	require.True(t, c.Ok())
+	require.NotZero(t, c.Edges)
+	require.Zero(t, c.Missing, c.String())
+
+	cg := unif.Payload.CallGraph
+	require.Contains(t, callees(cg.Nodes[main.Func("g")]), main.Func("h"))
+	require.Contains(t, callees(cg.Nodes[main.Func("main")]), main.Func("fin"))
+	require.Contains(t, callees(cg.Nodes[main.Func("try").AnonFuncs[0]]), prog.FuncValue(main.Type("E").Type().(*types.Named).Method(0)))
+}
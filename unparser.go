@@ -1,6 +1,8 @@
 package stamets
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
@@ -46,7 +48,10 @@ func UnparsePTAResultsFromReader(r io.Reader) []PTAMetrics {
 	// Relevant rows
 	const (
 		TITLE    = "PTA METRICS"
+		ANALYSIS = "- Analysis:"
 		DURATION = "- Duration:"
+		FUNCS    = "- Number of SSA functions:"
+		INSTRS   = "- Number of SSA instructions:"
 		QUERIES  = "- Number of PTA queries:"
 		IQUERIES = "- Number of indirect PTA queries:"
 		P50      = "- P50 points-to set size:"
@@ -54,6 +59,19 @@ func UnparsePTAResultsFromReader(r io.Reader) []PTAMetrics {
 		P99      = "- P99 points-to set size:"
 		MAX      = "- Max points-to set size:"
 		MODE     = "- Most common points-to set size:"
+
+		IP50  = "- P50 indirect points-to set size:"
+		IP90  = "- P90 indirect points-to set size:"
+		IP99  = "- P99 indirect points-to set size:"
+		IMAX  = "- Max indirect points-to set size:"
+		IMODE = "- Most common indirect points-to set size:"
+
+		SYNTHETIC   = "- Number of synthetic entry calls:"
+		WARNINGS    = "- Number of PTA warnings:"
+		RETAINED    = "- Retained result size (bytes):"
+		DEGRADATION = "- Degradation level:"
+		WCATEGORIES = "PTA warnings by category:"
+		WPOSITIONS  = "PTA warning positions:"
 	)
 
 	content := string(bs)
@@ -66,11 +84,17 @@ func UnparsePTAResultsFromReader(r io.Reader) []PTAMetrics {
 			},
 		}
 	}
-	current, unparsing := fresh(), false
+	// A block is complete once the most common points-to set size is unparsed.
+	// Rows following it are optional, and the block is flushed at the first
+	// row which does not belong to it.
+	// Warning categories and positions are listed in sections, one per row.
+	current, unparsing, complete, section := fresh(), false, false, ""
 	flush := func() {
 		results = append(results, current)
 		current = fresh()
 		unparsing = false
+		complete = false
+		section = ""
 	}
 
 	for _, l := range strings.Split(content, "\n") {
@@ -80,11 +104,30 @@ func UnparsePTAResultsFromReader(r io.Reader) []PTAMetrics {
 		} else if unparsing {
 			switch {
 			case strings.HasPrefix(l, TITLE):
+				if complete {
+					flush()
+					unparsing = true
+				}
 				current = fresh()
+				section = ""
+			case strings.HasPrefix(l, ANALYSIS):
+				for kind, name := range analysisKinds {
+					if getRowValue(ANALYSIS, l) == name {
+						current.Analysis = kind
+					}
+				}
 			case strings.HasPrefix(l, DURATION):
 				if t, err := time.ParseDuration(getRowValue(DURATION, l) + "s"); err == nil {
 					current.Duration = t
 				}
+			case strings.HasPrefix(l, FUNCS):
+				if v, err := strconv.Atoi(getRowValue(FUNCS, l)); err == nil {
+					current.Functions = v
+				}
+			case strings.HasPrefix(l, INSTRS):
+				if v, err := strconv.Atoi(getRowValue(INSTRS, l)); err == nil {
+					current.Instructions = v
+				}
 			case strings.HasPrefix(l, QUERIES):
 				if v, err := strconv.Atoi(getRowValue(QUERIES, l)); err == nil {
 					current.Queries = v
@@ -113,10 +156,76 @@ func UnparsePTAResultsFromReader(r io.Reader) []PTAMetrics {
 				if v, err := strconv.Atoi(getRowValue(MODE, l)); err == nil {
 					current.PointsToSetSizeMode = v
 				}
+				complete = true
+			case strings.HasPrefix(l, IP50):
+				if v, err := strconv.Atoi(getRowValue(IP50, l)); err == nil {
+					current.IndirectPointsToSetSizeP50 = v
+				}
+			case strings.HasPrefix(l, IP90):
+				if v, err := strconv.Atoi(getRowValue(IP90, l)); err == nil {
+					current.IndirectPointsToSetSizeP90 = v
+				}
+			case strings.HasPrefix(l, IP99):
+				if v, err := strconv.Atoi(getRowValue(IP99, l)); err == nil {
+					current.IndirectPointsToSetSizeP99 = v
+				}
+			case strings.HasPrefix(l, IMAX):
+				if v, err := strconv.Atoi(getRowValue(IMAX, l)); err == nil {
+					current.IndirectPointsToSetSizeMax = v
+				}
+			case strings.HasPrefix(l, IMODE):
+				if v, err := strconv.Atoi(getRowValue(IMODE, l)); err == nil {
+					current.IndirectPointsToSetSizeMode = v
+				}
+			case strings.HasPrefix(l, SYNTHETIC):
+				if v, err := strconv.Atoi(getRowValue(SYNTHETIC, l)); err == nil {
+					current.SyntheticEntryCalls = v
+				}
+			case strings.HasPrefix(l, WARNINGS):
+				if v, err := strconv.Atoi(getRowValue(WARNINGS, l)); err == nil {
+					current.Warnings = v
+				}
+			case strings.HasPrefix(l, RETAINED):
+				if v, err := strconv.ParseUint(getRowValue(RETAINED, l), 10, 64); err == nil {
+					current.RetainedBytes = v
+				}
+			case strings.HasPrefix(l, DEGRADATION):
+				for level, name := range degradationLevels {
+					if getRowValue(DEGRADATION, l) == name {
+						current.Degradation = level
+					}
+				}
+			case l == WCATEGORIES || l == WPOSITIONS:
+				section = l
+			case section != "" && strings.HasPrefix(l, "- "):
+				i := strings.LastIndex(l, ": ")
+				if i < 0 {
+					break
+				}
+				v, err := strconv.Atoi(l[i+2:])
+				if err != nil {
+					break
+				}
+				switch key := l[len("- "):i]; section {
+				case WCATEGORIES:
+					if current.WarningCategories == nil {
+						current.WarningCategories = make(map[string]int)
+					}
+					current.WarningCategories[key] = v
+				case WPOSITIONS:
+					current.WarningPositions = append(current.WarningPositions, WarningPosition{
+						Position: parsePosition(key),
+						Warnings: v,
+					})
+				}
+			case complete:
 				flush()
 			}
 		}
 	}
+	if complete {
+		flush()
+	}
 
 	return results
 }
@@ -230,3 +339,76 @@ func UnparseCallGraphMetricsFromReader(r io.Reader) []CallGraphMetrics {
 
 	return results
 }
+
+// UnparseCostModelFromReader reads the whole content of a reader and reconstructs
+// the first printed CostModel value. Coefficients are printed in their shortest exact
+// representation, such that the reconstructed model makes identical predictions.
+func UnparseCostModelFromReader(r io.Reader) (CostModel, error) {
+	bs, err := io.ReadAll(r)
+	if err != nil {
+		return CostModel{}, err
+	}
+
+	// Relevant rows
+	const (
+		TITLE    = "COST MODEL"
+		DSAMPLES = "- Duration samples:"
+		DURATION = "- Duration (s):"
+		MSAMPLES = "- Memory samples:"
+		MEMORY   = "- Retained result size (bytes):"
+	)
+
+	coefficients := func(v string) ([]float64, error) {
+		if v == "none" {
+			return nil, nil
+		}
+		terms := strings.Split(v, " + ")
+		if len(terms) != len(CostFeatures{}.vector()) {
+			return nil, fmt.Errorf("invalid cost model coefficients: %q", v)
+		}
+		cs := make([]float64, 0, len(terms))
+		for _, term := range terms {
+			c, err := strconv.ParseFloat(strings.Fields(term)[0], 64)
+			if err != nil {
+				return nil, err
+			}
+			cs = append(cs, c)
+		}
+		return cs, nil
+	}
+
+	var c CostModel
+	unparsing := false
+	for _, l := range strings.Split(string(bs), "\n") {
+		l = strings.TrimSpace(l)
+		if !unparsing {
+			unparsing = l == TITLE
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(l, DSAMPLES):
+			if c.DurationSamples, err = strconv.Atoi(getRowValue(DSAMPLES, l)); err != nil {
+				return CostModel{}, err
+			}
+		case strings.HasPrefix(l, DURATION):
+			if c.Duration, err = coefficients(getRowValue(DURATION, l)); err != nil {
+				return CostModel{}, err
+			}
+		case strings.HasPrefix(l, MSAMPLES):
+			if c.MemorySamples, err = strconv.Atoi(getRowValue(MSAMPLES, l)); err != nil {
+				return CostModel{}, err
+			}
+		case strings.HasPrefix(l, MEMORY):
+			if c.Memory, err = coefficients(getRowValue(MEMORY, l)); err != nil {
+				return CostModel{}, err
+			}
+		default:
+			return c, nil
+		}
+	}
+	if !unparsing {
+		return CostModel{}, errors.New("no cost model found")
+	}
+	return c, nil
+}
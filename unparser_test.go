@@ -107,6 +107,82 @@ func TestGetPTAResultsFromReader(t *testing.T) {
 	compare(1)
 }
 
+func TestGetPTAResultsFromReaderOptionalRows(t *testing.T) {
+	resultMetrics := UnparsePTAResultsFromReader(strings.NewReader(`
+	PTA METRICS
+	- Duration: 0.5
+	- Number of PTA queries: 100
+	- Number of indirect PTA queries: 10
+	- P50 points-to set size: 1
+	- P90 points-to set size: 2
+	- P99 points-to set size: 3
+	- Max points-to set size: 4
+	- Most common points-to set size: 5
+	- P50 indirect points-to set size: 1
+	- P90 indirect points-to set size: 2
+	- P99 indirect points-to set size: 3
+	- Max indirect points-to set size: 4
+	- Most common indirect points-to set size: 2
+	- Number of synthetic entry calls: 6
+	PTA METRICS
+	- Duration: 1
+	- Number of PTA queries: 200
+	- Number of indirect PTA queries: 20
+	- P50 points-to set size: 6
+	- P90 points-to set size: 7
+	- P99 points-to set size: 8
+	- Max points-to set size: 9
+	- Most common points-to set size: 10
+	Unrelated output
+	- Number of synthetic entry calls: 11
+		`))
+
+	require.Len(t, resultMetrics, 2)
+	require.Equal(t, 5, resultMetrics[0].PointsToSetSizeMode)
+	require.Equal(t, 6, resultMetrics[0].SyntheticEntryCalls)
+	require.Equal(t, 1, resultMetrics[0].IndirectPointsToSetSizeP50)
+	require.Equal(t, 2, resultMetrics[0].IndirectPointsToSetSizeP90)
+	require.Equal(t, 3, resultMetrics[0].IndirectPointsToSetSizeP99)
+	require.Equal(t, 4, resultMetrics[0].IndirectPointsToSetSizeMax)
+	require.Equal(t, 2, resultMetrics[0].IndirectPointsToSetSizeMode)
+	require.Equal(t, 10, resultMetrics[1].PointsToSetSizeMode)
+	// Rows following the end of a block are not unparsed.
+	require.Zero(t, resultMetrics[1].SyntheticEntryCalls)
+}
+
+func TestGetPTAResultsFromString(t *testing.T) {
+	m := PTAMetrics{
+		BaseMetrics: BaseMetrics[*pointer.Result]{
+			Duration: time.Second,
+		},
+		Functions:                   12,
+		Instructions:                345,
+		Queries:                     100,
+		IndirectQueries:             10,
+		PointsToSetSizeP50:          1,
+		PointsToSetSizeP90:          2,
+		PointsToSetSizeP99:          3,
+		PointsToSetSizeMax:          4,
+		PointsToSetSizeMode:         1,
+		IndirectPointsToSetSizeP50:  5,
+		IndirectPointsToSetSizeP90:  6,
+		IndirectPointsToSetSizeP99:  7,
+		IndirectPointsToSetSizeMax:  8,
+		IndirectPointsToSetSizeMode: 5,
+		SyntheticEntryCalls:         9,
+		Warnings:                    10,
+		RetainedBytes:               1 << 40,
+		Degradation:                 DegradationNoIndirectQueries,
+		Analysis:                    AnalysisUnification,
+	}
+
+	resultMetrics := UnparsePTAResultsFromReader(strings.NewReader(m.String()))
+	require.Len(t, resultMetrics, 1)
+	require.NotNil(t, resultMetrics[0].Payload)
+	resultMetrics[0].Payload = nil
+	require.Equal(t, m, resultMetrics[0])
+}
+
 func TestGetCallGraphResultsFromReader(t *testing.T) {
 	require.Empty(t, UnparseCallGraphMetricsFromReader(strings.NewReader("")))
 
@@ -234,3 +310,26 @@ func TestGetCallGraphResultsFromReader(t *testing.T) {
 	compare(0)
 	compare(1)
 }
+
+func TestGetCostModelFromString(t *testing.T) {
+	c := CostModel{
+		DurationSamples: 20,
+		MemorySamples:   3,
+		Duration:        []float64{-0.1, 1.0 / 3, 1e-9, 12345.678, 0},
+		Memory:          []float64{1 << 20, -2.5, 0.001, 7, 1e12},
+	}
+	res, err := UnparseCostModelFromReader(strings.NewReader("Fitting...\n" + c.String() + "Done.\n"))
+	require.NoError(t, err)
+	require.Equal(t, c, res)
+
+	// Missing memory models are preserved.
+	c.MemorySamples, c.Memory = 0, nil
+	res, err = UnparseCostModelFromReader(strings.NewReader(c.String()))
+	require.NoError(t, err)
+	require.Equal(t, c, res)
+
+	_, err = UnparseCostModelFromReader(strings.NewReader("PTA METRICS\n"))
+	require.Error(t, err)
+	_, err = UnparseCostModelFromReader(strings.NewReader("COST MODEL\n- Duration (s): 1 + 2 * functions\n"))
+	require.Error(t, err)
+}
@@ -1,6 +1,8 @@
 package stamets
 
 import (
+	"fmt"
+
 	"golang.org/x/exp/constraints"
 	"golang.org/x/exp/slices"
 )
@@ -103,3 +105,53 @@ func mode[T comparable](list []T) T {
 
 	return m
 }
+
+// Distribution summarizes a series of sizes.
+type Distribution struct {
+	P50  int
+	P90  int
+	P99  int
+	Max  int
+	Mode int
+}
+
+func (d Distribution) String() string {
+	return fmt.Sprintf("P50 %d, P90 %d, P99 %d, Max %d, Mode %d", d.P50, d.P90, d.P99, d.Max, d.Mode)
+}
+
+// makeDistribution summarizes a series of sizes, which is sorted in place.
+func makeDistribution(sizes []int) Distribution {
+	slices.Sort(sizes)
+	return Distribution{
+		P50:  p50(sizes),
+		P90:  p90(sizes),
+		P99:  p99(sizes),
+		Max:  Series[int](sizes).Max(),
+		Mode: mode(sizes),
+	}
+}
+
+// RatioDistribution summarizes a series of ratios.
+type RatioDistribution struct {
+	P50  float64
+	P90  float64
+	P99  float64
+	Max  float64
+	Mode float64
+}
+
+func (d RatioDistribution) String() string {
+	return fmt.Sprintf("P50 %.2f, P90 %.2f, P99 %.2f, Max %.2f, Mode %.2f", d.P50, d.P90, d.P99, d.Max, d.Mode)
+}
+
+// makeRatioDistribution summarizes a series of ratios, which is sorted in place.
+func makeRatioDistribution(ratios []float64) RatioDistribution {
+	slices.Sort(ratios)
+	return RatioDistribution{
+		P50:  p50(ratios),
+		P90:  p90(ratios),
+		P99:  p99(ratios),
+		Max:  Series[float64](ratios).Max(),
+		Mode: mode(ratios),
+	}
+}
@@ -0,0 +1,148 @@
+package stamets
+
+import (
+	"fmt"
+	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
+)
+
+// topWarningPositions is the number of source positions reported
+// in PTA warning metrics.
+const topWarningPositions = 10
+
+// warningTemplates are the message templates of the warnings reported by the
+// points-to analysis. Warnings are categorized by template.
+var warningTemplates = func() map[string]*regexp.Regexp {
+	templates := []string{
+		"unsound call to unknown intrinsic: %s",
+		"unsound call to generic function body: %s (build with ssa.InstantiateGenerics)",
+		"unsound call to instantiation wrapper of generic: %s (build with ssa.InstantiateGenerics)",
+		"unsound: intrinsic treatment of %s not yet implemented",
+		"unsound: %s contains a reflect.NewAt() call",
+	}
+
+	res := make(map[string]*regexp.Regexp, len(templates))
+	for _, t := range templates {
+		res[t] = regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(t), "%s", ".*") + "$")
+	}
+	return res
+}()
+
+// declaredHere is the message of the notes following warnings about calls to unknown intrinsics,
+// positioned at the declaration of the intrinsic. Notes are not warnings on their own.
+const declaredHere = "(declared here)"
+
+// warningTemplate strips the dynamic parts of a warning message. Messages
+// which do not match a known template are their own template.
+func warningTemplate(msg string) string {
+	msg = strings.TrimSpace(msg)
+	for t, re := range warningTemplates {
+		if re.MatchString(msg) {
+			return t
+		}
+	}
+	return msg
+}
+
+// WarningPosition is a source position that triggered PTA warnings.
+type WarningPosition struct {
+	Position token.Position
+	// Number of warnings at the position
+	Warnings int
+}
+
+// WarningMetrics counts the warnings of the points-to analysis, by message template
+// and by source position, resolved in the given file set. Notes pointing to the
+// declarations of unknown intrinsics are not counted.
+func (m PTAMetrics) WarningMetrics(fset *token.FileSet) PTAMetrics {
+	m.Warnings = 0
+	categories := make(map[string]int)
+	positions := make(map[token.Position]int)
+	for _, w := range m.Payload.Warnings {
+		if strings.TrimSpace(w.Message) == declaredHere {
+			continue
+		}
+		m.Warnings++
+		categories[warningTemplate(w.Message)]++
+		positions[fset.Position(w.Pos)]++
+	}
+	if m.Warnings == 0 {
+		return m
+	}
+
+	m.WarningCategories = categories
+
+	m.WarningPositions = make([]WarningPosition, 0, len(positions))
+	for pos, n := range positions {
+		m.WarningPositions = append(m.WarningPositions, WarningPosition{Position: pos, Warnings: n})
+	}
+	slices.SortFunc(m.WarningPositions, func(a, b WarningPosition) bool {
+		if a.Warnings != b.Warnings {
+			return a.Warnings > b.Warnings
+		}
+		return positionLess(a.Position, b.Position)
+	})
+	if len(m.WarningPositions) > topWarningPositions {
+		m.WarningPositions = m.WarningPositions[:topWarningPositions]
+	}
+
+	return m
+}
+
+// warningsString renders the warning categories and positions, if any.
+func (m PTAMetrics) warningsString() string {
+	str := ""
+	if len(m.WarningCategories) > 0 {
+		str += "PTA warnings by category:\n"
+		categories := maps.Keys(m.WarningCategories)
+		slices.SortFunc(categories, func(a, b string) bool {
+			if m.WarningCategories[a] != m.WarningCategories[b] {
+				return m.WarningCategories[a] > m.WarningCategories[b]
+			}
+			return a < b
+		})
+		for _, c := range categories {
+			str += fmt.Sprintf("\t- %s: %d\n", c, m.WarningCategories[c])
+		}
+	}
+	if len(m.WarningPositions) > 0 {
+		str += "PTA warning positions:\n"
+		for _, p := range m.WarningPositions {
+			str += fmt.Sprintf("\t- %s: %d\n", p.Position, p.Warnings)
+		}
+	}
+	return str
+}
+
+// parsePosition parses a source position, as printed by token.Position.String.
+func parsePosition(s string) token.Position {
+	var pos token.Position
+	if s == "-" {
+		return pos
+	}
+
+	pos.Filename = s
+	// Line and column are optional, and file names may contain colons.
+	for _, n := range []*int{&pos.Column, &pos.Line} {
+		i := strings.LastIndex(pos.Filename, ":")
+		if i < 0 {
+			break
+		}
+		v, err := strconv.Atoi(pos.Filename[i+1:])
+		if err != nil {
+			break
+		}
+		*n = v
+		pos.Filename = pos.Filename[:i]
+	}
+	if pos.Line == 0 && pos.Column != 0 {
+		// Only the line was present.
+		pos.Line, pos.Column = pos.Column, 0
+	}
+	return pos
+}
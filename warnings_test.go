@@ -0,0 +1,75 @@
+package stamets
+
+import (
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/tools/go/pointer"
+)
+
+func TestWarningTemplate(t *testing.T) {
+	require.Equal(t, "unsound call to unknown intrinsic: %s",
+		warningTemplate("unsound call to unknown intrinsic: (*sync.Mutex).Lock"))
+	require.Equal(t, "unsound call to generic function body: %s (build with ssa.InstantiateGenerics)",
+		warningTemplate("unsound call to generic function body: lib.F[T any] (build with ssa.InstantiateGenerics)"))
+	require.Equal(t, "unsound: %s contains a reflect.NewAt() call",
+		warningTemplate("unsound: lib.F contains a reflect.NewAt() call"))
+	require.Equal(t, "unknown warning", warningTemplate("unknown warning"))
+}
+
+func TestParsePosition(t *testing.T) {
+	for _, pos := range []token.Position{
+		{},
+		{Filename: "a.go"},
+		{Filename: "a.go", Line: 1},
+		{Filename: "a.go", Line: 1, Column: 2},
+		{Filename: "C:/a.go", Line: 1, Column: 2},
+	} {
+		require.Equal(t, pos, parsePosition(pos.String()))
+	}
+}
+
+func TestWarningMetrics(t *testing.T) {
+	fset := token.NewFileSet()
+	f := fset.AddFile("a.go", -1, 100)
+	f.SetLines([]int{0, 10, 20})
+
+	m := PTAMetrics{
+		BaseMetrics: BaseMetrics[*pointer.Result]{
+			Payload: &pointer.Result{
+				Warnings: []pointer.Warning{
+					{Pos: f.Pos(12), Message: "unsound call to unknown intrinsic: f"},
+					{Pos: f.Pos(1), Message: " (declared here)"},
+					{Pos: f.Pos(12), Message: "unsound call to unknown intrinsic: g"},
+					{Pos: f.Pos(25), Message: "unsound: h contains a reflect.NewAt() call"},
+				},
+			},
+		},
+	}
+
+	// Notes following warnings are not counted.
+	m = m.WarningMetrics(fset)
+	require.Equal(t, 3, m.Warnings)
+	require.Equal(t, map[string]int{
+		"unsound call to unknown intrinsic: %s":       2,
+		"unsound: %s contains a reflect.NewAt() call": 1,
+	}, m.WarningCategories)
+	require.Equal(t, []WarningPosition{
+		{Position: token.Position{Filename: "a.go", Offset: 12, Line: 2, Column: 3}, Warnings: 2},
+		{Position: token.Position{Filename: "a.go", Offset: 25, Line: 3, Column: 6}, Warnings: 1},
+	}, m.WarningPositions)
+
+	// Warnings survive unparsing, except for position offsets.
+	res := UnparsePTAResultsFromReader(strings.NewReader(m.String()))
+	require.Len(t, res, 1)
+	require.Equal(t, m.Warnings, res[0].Warnings)
+	require.Equal(t, m.WarningCategories, res[0].WarningCategories)
+	require.Len(t, res[0].WarningPositions, 2)
+	for i, p := range res[0].WarningPositions {
+		expected := m.WarningPositions[i]
+		expected.Position.Offset = 0
+		require.Equal(t, expected, p)
+	}
+}